@@ -17,7 +17,13 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
 	"github.com/fnrunner/fnutils/pkg/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -83,13 +89,23 @@ func (r *ControllerConfigSpec) GetServices() map[string]*Function {
 	return r.Services
 }
 
+// GetServiceNames returns the names of the services sorted alphabetically
+func (r *ControllerConfigSpec) GetServiceNames() []string {
+	names := make([]string, 0, len(r.Services))
+	for name := range r.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (r *ControllerConfigSpec) GetPipelines() []*Pipeline {
 	return r.Pipelines
 }
 
 func (r *ControllerConfigSpec) GetPipeline(s string) *Pipeline {
 	for _, pipeline := range r.GetPipelines() {
-		if pipeline.Name == s {
+		if pipeline != nil && pipeline.Name == s {
 			return pipeline
 		}
 	}
@@ -104,6 +120,54 @@ func GetIdxName(idxName string) (string, int) {
 }
 */
 
+// HasConfig returns true if a config is supplied
+func (v *Function) HasConfig() bool {
+	return len(v.Config.Raw) != 0 && string(v.Config.Raw) != "null"
+}
+
+// GetConfigString returns the config if it is supplied as a string
+func (v *Function) GetConfigString() (string, bool) {
+	if !v.HasConfig() {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(v.Config.Raw, &s); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// SetConfigString sets the config as a string. Config used to be a string
+// field, the previous assignment of a string converts to SetConfigString.
+func (v *Function) SetConfigString(s string) {
+	// marshaling a string cannot fail
+	b, _ := json.Marshal(s)
+	v.Config = runtime.RawExtension{Raw: b}
+}
+
+// GetConfigObject returns the config if it is supplied as an inline KRM object
+func (v *Function) GetConfigObject() (*unstructured.Unstructured, error) {
+	if !v.HasConfig() {
+		return nil, fmt.Errorf("no config supplied")
+	}
+	u := &unstructured.Unstructured{}
+	if err := json.Unmarshal(v.Config.Raw, &u.Object); err != nil {
+		return nil, fmt.Errorf("config is not an object: %s", err.Error())
+	}
+	return u, nil
+}
+
+// HasRuntimeSettings returns true if one of the runtime settings of the
+// executor is set
+func (v *Executor) HasRuntimeSettings() bool {
+	return v.Resources != nil || len(v.Env) != 0 || len(v.EnvFrom) != 0 || v.Timeout != nil || v.Retry != nil
+}
+
+// HasServiceSettings returns true if one of the service specific settings is set
+func (v *ServiceSettings) HasServiceSettings() bool {
+	return v.Replicas != nil || v.PortName != "" || v.ReadinessProbe != nil || v.LivenessProbe != nil
+}
+
 func (v *Function) HasBlock() bool {
 	return v.Block.Range != nil || v.Block.Condition != nil
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -65,15 +66,16 @@ type ConditionExpression struct {
 type FunctionType string
 
 const (
-	RootType       FunctionType = "root"
-	QueryType      FunctionType = "query"
-	SliceType      FunctionType = "slice"
-	MapType        FunctionType = "map"
-	JQType         FunctionType = "jq"
-	ContainerType  FunctionType = "container"
-	WasmType       FunctionType = "wasm"
-	GoTemplateType FunctionType = "gotemplate"
-	BlockType      FunctionType = "block"
+	RootType        FunctionType = "root"
+	QueryType       FunctionType = "query"
+	SliceType       FunctionType = "slice"
+	MapType         FunctionType = "map"
+	JQType          FunctionType = "jq"
+	ContainerType   FunctionType = "container"
+	WasmType        FunctionType = "wasm"
+	GoTemplateType  FunctionType = "gotemplate"
+	BlockType       FunctionType = "block"
+	ServiceCallType FunctionType = "servicecall"
 )
 
 type FunctionElement struct {
@@ -84,17 +86,31 @@ type FunctionElement struct {
 type Function struct {
 	Block    `json:",inline" yaml:",inline"`
 	Executor `json:",inline" yaml:",inline"`
+	// ServiceSettings are only applicable to functions in the services section
+	ServiceSettings `json:",inline" yaml:",inline"`
 	// Vars define the local variables in the function
 	// The Key respresents the local variable name
 	// The Value represents the jq expression
-	Vars   map[string]string `json:"vars,omitempty" yaml:"vars,omitempty"`
-	Type   FunctionType      `json:"type,omitempty" yaml:"type,omitempty"`
-	Config string            `json:"config,omitempty" yaml:"config,omitempty"`
+	Vars map[string]string `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Type FunctionType      `json:"type,omitempty" yaml:"type,omitempty"`
+	// Config is either a string or an inline KRM object. A string config keeps
+	// its yaml and json format, in go it is read with GetConfigString and set
+	// with SetConfigString.
+	Config runtime.RawExtension `json:"config,omitempty" yaml:"config,omitempty"`
 	// input is always a GVK of some sort
 	Input *Input `json:"input,omitempty" yaml:"input,omitempty"`
 	// key = variableName, value is gvr format or not -> gvr format is needed for external resources
 	Output    map[string]*Output `json:"output,omitempty" yaml:"output,omitempty"`
 	DependsOn []string           `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+	// ServiceRef references the service a servicecall function consumes
+	ServiceRef *ServiceRef `json:"serviceRef,omitempty" yaml:"serviceRef,omitempty"`
+}
+
+type ServiceRef struct {
+	// Name of the service in the services section
+	Name string `json:"name" yaml:"name"`
+	// Output is the output variable of the service the function consumes
+	Output string `json:"output" yaml:"output"`
 }
 
 type Output struct {
@@ -104,16 +120,50 @@ type Output struct {
 }
 
 type Input struct {
-	Selector     *metav1.LabelSelector `json:"selector,omitempty" yaml:"selector,omitempty"`
-	Key          string                `json:"key,omitempty" yaml:"key,omitempty"`
-	Value        string                `json:"value,omitempty" yaml:"value,omitempty"`
-	GenericInput map[string]string     `json:",inline" yaml:",inline"`
-	Expression   string                `json:"expression,omitempty" yaml:"expression,omitempty"`
-	Resource     runtime.RawExtension  `json:"resource,omitempty" yaml:"resource,omitempty"`
-	Template     string                `json:"template,omitempty" yaml:"template,omitempty"`
+	Selector *metav1.LabelSelector `json:"selector,omitempty" yaml:"selector,omitempty"`
+	// Namespace scopes a query to a namespace, either static or a variable reference
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// FieldSelector filters the query on fields, e.g. spec.nodeName=$node
+	FieldSelector string               `json:"fieldSelector,omitempty" yaml:"fieldSelector,omitempty"`
+	Key           string               `json:"key,omitempty" yaml:"key,omitempty"`
+	Value         string               `json:"value,omitempty" yaml:"value,omitempty"`
+	GenericInput  map[string]string    `json:",inline" yaml:",inline"`
+	Expression    string               `json:"expression,omitempty" yaml:"expression,omitempty"`
+	Resource      runtime.RawExtension `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Template      string               `json:"template,omitempty" yaml:"template,omitempty"`
 }
 
 type Executor struct {
 	Image string `json:"image,omitempty" yaml:"image,omitempty"`
 	Exec  string `json:"exec,omitempty" yaml:"exec,omitempty"`
+	// Resources define the compute resources of the executor
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty" yaml:"resources,omitempty"`
+	// Env defines the environment variables of the executor, values can be
+	// sourced from ConfigMaps or Secrets
+	Env []corev1.EnvVar `json:"env,omitempty" yaml:"env,omitempty"`
+	// EnvFrom defines ConfigMaps or Secrets to populate the environment variables from
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty" yaml:"envFrom,omitempty"`
+	// Timeout of a single execution of the function, not supported in services
+	Timeout *metav1.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Retry defines how a failed execution of the function is retried, not
+	// supported in services
+	Retry *Retry `json:"retry,omitempty" yaml:"retry,omitempty"`
+}
+
+type Retry struct {
+	// Count is the maximum number of retries after a failed execution
+	Count int32 `json:"count" yaml:"count"`
+	// Backoff is the delay between retries
+	Backoff *metav1.Duration `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+}
+
+type ServiceSettings struct {
+	// Replicas is the number of replicas of the service, defaults to 1
+	Replicas *int32 `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	// PortName is the name of the service port, defaults to grpc
+	PortName string `json:"portName,omitempty" yaml:"portName,omitempty"`
+	// ReadinessProbe of the service, defaults to a grpc probe on the service port
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty" yaml:"readinessProbe,omitempty"`
+	// LivenessProbe of the service
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty" yaml:"livenessProbe,omitempty"`
 }
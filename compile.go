@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"github.com/fnrunner/fnsyntax/pkg/ccsyntax"
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/yaml"
+)
+
+// compile parses the controller config and writes the compiled config
+// execution context as an artifact, such that the runtime can load it
+// without parsing the config again
+//
+//	fnsyntax compile [-o artifact.json] [config.yaml]
+func compile(l logr.Logger, args []string) {
+	fs := flag.NewFlagSet("compile", flag.ContinueOnError)
+	out := fs.String("o", "artifact.json", "file the artifact is written to")
+	name := fs.String("name", "ctrlName", "name of the controller")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		l.Error(err, "cannot parse arguments")
+		os.Exit(2)
+	}
+
+	cfgFile := yamlFile
+	if fs.NArg() > 0 {
+		cfgFile = fs.Arg(0)
+	}
+
+	fb, err := os.ReadFile(cfgFile)
+	if err != nil {
+		l.Error(err, "cannot read file", "file", cfgFile)
+		os.Exit(1)
+	}
+	ctrlcfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal(fb, ctrlcfg); err != nil {
+		l.Error(err, "cannot unmarshal", "file", cfgFile)
+		os.Exit(1)
+	}
+
+	p, result := ccsyntax.NewParser(*name, ctrlcfg)
+	if len(result) > 0 {
+		for _, res := range result {
+			l.Error(errors.New(res.Error), "ccsyntax validation failed", "result", res)
+		}
+		os.Exit(1)
+	}
+	a, result := p.Compile()
+	if len(result) != 0 {
+		for _, res := range result {
+			l.Error(errors.New(res.Error), "ccsyntax compile failed", "result", res)
+		}
+		os.Exit(1)
+	}
+	b, err := a.JSON()
+	if err != nil {
+		l.Error(err, "cannot marshal artifact")
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, b, 0644); err != nil {
+		l.Error(err, "cannot write artifact", "file", *out)
+		os.Exit(1)
+	}
+	l.Info("ccsyntax compile succeeded", "artifact", *out, "hash", a.Hash, "configHash", a.ConfigHash)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
@@ -17,6 +18,11 @@ func main() {
 	ctrl.SetLogger(zap.New())
 	l := ctrl.Log.WithName("fnrun sytax")
 
+	if len(os.Args) > 1 && os.Args[1] == "compile" {
+		compile(l, os.Args[2:])
+		return
+	}
+
 	fb, err := os.ReadFile(yamlFile)
 	if err != nil {
 		l.Error(err, "cannot read file")
@@ -38,15 +44,25 @@ func main() {
 	}
 	l.Info("ccsyntax validation succeeded")
 
-	_, result = p.Parse()
+	ceCtx, result := p.Parse()
 	if len(result) != 0 {
 		for _, res := range result {
-			l.Error(err, "ccsyntax parsing failed", "result", res)
+			l.Error(errors.New(res.Error), "ccsyntax parsing failed", "result", res)
 		}
 		os.Exit(1)
 	}
 	l.Info("ccsyntax parsing succeeded")
 
+	for gvk, svcCtx := range ceCtx.GetServices() {
+		l.Info("service", "name", svcCtx.Name, "port", svcCtx.Port, "gvk", gvk)
+	}
+
+	// the ownership findings are warnings, such that configs that do not
+	// maintain the own section yet keep working
+	for _, res := range p.ValidateOwnership() {
+		l.Info("ccsyntax ownership warning", "result", res)
+	}
+
 	gvks, result := p.GetExternalResources()
 	if len(result) > 0 {
 		l.Error(err, "ccsyntax get external resources failed", "result", result)
@@ -61,4 +77,71 @@ func main() {
 	for _, image := range p.GetImages() {
 		l.Info("image", "imageInfo", image)
 	}
+
+	result = p.ValidateImages(&ccsyntax.ImagePolicy{
+		ForbiddenTags: []string{"latest"},
+		Severity:      ccsyntax.SeverityWarning,
+	})
+	for _, res := range result {
+		l.Info("ccsyntax image policy violation", "result", res)
+	}
+	if ccsyntax.HasErrors(result) {
+		os.Exit(1)
+	}
+
+	result = p.LintExec(&ccsyntax.ExecPolicy{
+		LocalDev: true,
+	})
+	for _, res := range result {
+		l.Info("ccsyntax exec lint", "result", res)
+	}
+	if ccsyntax.HasErrors(result) {
+		os.Exit(1)
+	}
+
+	inv, result := p.GetImageInventory()
+	if len(result) > 0 {
+		for _, res := range result {
+			l.Error(errors.New(res.Error), "ccsyntax get image inventory failed", "result", res)
+		}
+		os.Exit(1)
+	}
+	b, err := inv.YAML()
+	if err != nil {
+		l.Error(err, "cannot marshal image inventory")
+		os.Exit(1)
+	}
+	l.Info("image inventory", "inventory", string(b))
+
+	cr, result := p.GetClusterRole("ctrlName")
+	if len(result) > 0 {
+		for _, res := range result {
+			l.Error(errors.New(res.Error), "ccsyntax get cluster role failed", "result", res)
+		}
+		os.Exit(1)
+	}
+	b, err = yaml.Marshal(cr)
+	if err != nil {
+		l.Error(err, "cannot marshal cluster role")
+		os.Exit(1)
+	}
+	l.Info("clusterrole", "manifest", string(b))
+
+	svcManifests, result := p.GetServiceManifests("default")
+	if len(result) > 0 {
+		for _, res := range result {
+			l.Error(errors.New(res.Error), "ccsyntax get service manifests failed", "result", res)
+		}
+		os.Exit(1)
+	}
+	for _, m := range svcManifests {
+		for _, o := range []any{m.Deployment, m.Service} {
+			b, err = yaml.Marshal(o)
+			if err != nil {
+				l.Error(err, "cannot marshal service manifest")
+				os.Exit(1)
+			}
+			l.Info("service manifest", "service", m.Name, "manifest", string(b))
+		}
+	}
 }
@@ -0,0 +1,230 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ccsyntaxtest provides a golden file test harness for controller
+// configs. A test case is a directory holding a config.yaml or a config.ref
+// that refers to a config elsewhere, the harness parses the config and
+// compares the results, the dags, the external resources and the images with
+// the golden files in the directory. Run the tests with -update to regenerate
+// the golden files.
+//
+// A config.ref holds the path of the config relative to the test case
+// directory and the sha256 of the config the golden files were generated
+// from:
+//
+//	../../../../examples/upf.yaml sha256:<hex>
+//
+// A case fails when the config that is referred to changes, such that an
+// edit of a config outside the testdata cannot silently change what the
+// golden files cover. The -update flag also updates the sha256.
+package ccsyntaxtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fnrunv1alpha1 "github.com/fnrunner/fnruntime/apis/fnrun/v1alpha1"
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"github.com/fnrunner/fnsyntax/pkg/ccsyntax"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the controller config test cases")
+
+const (
+	// ConfigFile is the controller config of a test case
+	ConfigFile = "config.yaml"
+	// ConfigRefFile holds the path of the controller config of a test case,
+	// relative to the test case directory, and its sha256
+	ConfigRefFile = "config.ref"
+	// ResultsFile is the golden file of the validation and parse results
+	ResultsFile = "results.golden.yaml"
+	// DAGFile is the golden file of the dags
+	DAGFile = "dag.golden"
+	// ExternalResourcesFile is the golden file of the external resources
+	ExternalResourcesFile = "external_resources.golden.yaml"
+	// ImagesFile is the golden file of the images
+	ImagesFile = "images.golden.yaml"
+)
+
+// Results are the results of a test case
+type Results struct {
+	Validate []ccsyntax.Result `json:"validate,omitempty"`
+	Parse    []ccsyntax.Result `json:"parse,omitempty"`
+}
+
+// Run runs every directory in dir that holds a config.yaml or a config.ref as
+// a test case
+func Run(t *testing.T, dir string, opts ...ccsyntax.Option) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("cannot read test cases: %s", err.Error())
+	}
+	cases := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		caseDir := filepath.Join(dir, entry.Name())
+		if _, _, err := configPath(caseDir); err != nil {
+			continue
+		}
+		cases++
+		t.Run(entry.Name(), func(t *testing.T) {
+			RunCase(t, caseDir, opts...)
+		})
+	}
+	if cases == 0 {
+		t.Fatalf("no test cases found in %s", dir)
+	}
+}
+
+// RunCase parses the config of the test case in dir and compares the output
+// with the golden files. The config is parsed with ParseAll, such that the
+// results of all the phases are compared.
+func RunCase(t *testing.T, dir string, opts ...ccsyntax.Option) {
+	t.Helper()
+	path, ref, err := configPath(dir)
+	if err != nil {
+		t.Fatalf("cannot find config: %s", err.Error())
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read config: %s", err.Error())
+	}
+	if ref {
+		checkConfigRef(t, dir, b)
+	}
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		t.Fatalf("cannot unmarshal config: %s", err.Error())
+	}
+
+	p, validateResults := ccsyntax.NewParser(filepath.Base(dir), cfg, opts...)
+	ceCtx, parseResults := p.ParseAll()
+	compareYAML(t, dir, ResultsFile, &Results{Validate: validateResults, Parse: parseResults})
+
+	var dag bytes.Buffer
+	if ceCtx != nil {
+		ceCtx.Fprint(&dag)
+	}
+	compare(t, dir, DAGFile, dag.Bytes())
+
+	gvks, _ := p.GetExternalResources()
+	if gvks == nil {
+		gvks = []*schema.GroupVersionKind{}
+	}
+	compareYAML(t, dir, ExternalResourcesFile, gvks)
+
+	images := p.GetImages()
+	if images == nil {
+		images = []*fnrunv1alpha1.Image{}
+	}
+	compareYAML(t, dir, ImagesFile, images)
+}
+
+// configPath returns the path of the config of the test case in dir and
+// whether a config.ref refers to it
+func configPath(dir string) (string, bool, error) {
+	path := filepath.Join(dir, ConfigFile)
+	if _, err := os.Stat(path); err == nil {
+		return path, false, nil
+	}
+	ref, _, err := readConfigRef(dir)
+	if err != nil {
+		return "", false, err
+	}
+	path = filepath.Join(dir, ref)
+	if _, err := os.Stat(path); err != nil {
+		return "", false, err
+	}
+	return path, true, nil
+}
+
+// readConfigRef returns the path and the sha256 in the config.ref of the
+// test case in dir, the sha256 is empty when the config.ref has none
+func readConfigRef(dir string) (string, string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, ConfigRefFile))
+	if err != nil {
+		return "", "", err
+	}
+	fields := strings.Fields(string(b))
+	switch len(fields) {
+	case 1:
+		return fields[0], "", nil
+	case 2:
+		return fields[0], strings.TrimPrefix(fields[1], "sha256:"), nil
+	default:
+		return "", "", fmt.Errorf("%s must hold a path and a sha256, got %q", ConfigRefFile, string(b))
+	}
+}
+
+// checkConfigRef checks the sha256 of the config the config.ref of the test
+// case in dir refers to, -update writes the sha256 of the config
+func checkConfigRef(t *testing.T, dir string, config []byte) {
+	t.Helper()
+	ref, want, err := readConfigRef(dir)
+	if err != nil {
+		t.Fatalf("cannot read config ref: %s", err.Error())
+	}
+	sum := sha256.Sum256(config)
+	got := hex.EncodeToString(sum[:])
+	if *update {
+		if err := os.WriteFile(filepath.Join(dir, ConfigRefFile), []byte(fmt.Sprintf("%s sha256:%s\n", ref, got)), 0644); err != nil {
+			t.Fatalf("cannot update config ref: %s", err.Error())
+		}
+		return
+	}
+	if got != want {
+		t.Fatalf("%s has sha256 %s, the golden files were generated from sha256 %q, run with -update to regenerate them", ref, got, want)
+	}
+}
+
+func compareYAML(t *testing.T, dir, file string, v any) {
+	t.Helper()
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		t.Fatalf("cannot marshal %s: %s", file, err.Error())
+	}
+	compare(t, dir, file, b)
+}
+
+func compare(t *testing.T, dir, file string, got []byte) {
+	t.Helper()
+	path := filepath.Join(dir, file)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("cannot update golden file: %s", err.Error())
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read golden file, run with -update to create it: %s", err.Error())
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match the golden file, run with -update to regenerate it\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
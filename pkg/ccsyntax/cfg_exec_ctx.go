@@ -18,8 +18,12 @@ package ccsyntax
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"sync"
 
+	"github.com/fnrunner/fnruntime/pkg/exec/output"
 	"github.com/fnrunner/fnruntime/pkg/exec/rtdag"
 	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -33,26 +37,38 @@ type ConfigExecutionContext interface {
 	GetDAGCtx(fow FOWS, gvk *schema.GroupVersionKind, op Operation) *RTDAGCtx
 	GetFOW(fow FOWS) map[schema.GroupVersionKind]OperationCtx
 	GetForGVK() *schema.GroupVersionKind
-	//AddService(gvk *schema.GroupVersionKind, fn ctrlcfgv1alpha1.Function) error
-	//GetServices() service.Services
+	AddService(name string, fn ctrlcfgv1alpha1.Function) error
+	AddServiceGVK(name string, gvk *schema.GroupVersionKind) error
+	GetService(name string) *ServiceCtx
+	GetServices() map[schema.GroupVersionKind]*ServiceCtx
 	Print()
+	Fprint(w io.Writer)
 }
 
+// serviceBasePort is the port of the first service, subsequent services
+// get the next port
+const serviceBasePort = 9000
+
 type cfgExecContext struct {
 	name  string
 	m     sync.RWMutex
 	For   map[schema.GroupVersionKind]OperationCtx
 	own   map[schema.GroupVersionKind]OperationCtx
 	watch map[schema.GroupVersionKind]OperationCtx
-	//serviceIdx int
-	//services   map[schema.GroupVersionKind]ServiceCtx
+	// services are indexed by name, serviceGVKs maps the output gvk
+	// to the service name
+	serviceIdx  int
+	services    map[string]*ServiceCtx
+	serviceGVKs map[schema.GroupVersionKind]string
 }
 
 type OperationCtx map[Operation]*RTDAGCtx
 
 type ServiceCtx struct {
+	Name string
 	Port int
 	Fn   ctrlcfgv1alpha1.Function
+	GVKs []schema.GroupVersionKind
 }
 
 type RTDAGCtx struct {
@@ -60,16 +76,40 @@ type RTDAGCtx struct {
 	RootVertexName string
 	m              sync.RWMutex
 	BlockDAGs      map[string]rtdag.RuntimeDAG
+	// ServiceEdges annotate the dependencies of the vertices on services
+	ServiceEdges []*ServiceEdge
+}
+
+// ServiceEdge is a dependency of a vertex on the output of a service
+type ServiceEdge struct {
+	Service    string
+	Output     string
+	GVK        schema.GroupVersionKind
+	VertexName string
+}
+
+func (r *RTDAGCtx) AddServiceEdge(e *ServiceEdge) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.ServiceEdges = append(r.ServiceEdges, e)
+}
+
+func (r *RTDAGCtx) GetServiceEdges() []*ServiceEdge {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	edges := make([]*ServiceEdge, len(r.ServiceEdges))
+	copy(edges, r.ServiceEdges)
+	return edges
 }
 
 func NewConfigExecutionContext(n string) ConfigExecutionContext {
 	return &cfgExecContext{
-		name: n,
-		//serviceIdx: 0,
-		For:   make(map[schema.GroupVersionKind]OperationCtx),
-		own:   make(map[schema.GroupVersionKind]OperationCtx),
-		watch: make(map[schema.GroupVersionKind]OperationCtx),
-		//services:   make(map[schema.GroupVersionKind]ServiceCtx),
+		name:        n,
+		For:         make(map[schema.GroupVersionKind]OperationCtx),
+		own:         make(map[schema.GroupVersionKind]OperationCtx),
+		watch:       make(map[schema.GroupVersionKind]OperationCtx),
+		services:    make(map[string]*ServiceCtx),
+		serviceGVKs: make(map[schema.GroupVersionKind]string),
 	}
 }
 
@@ -124,6 +164,9 @@ func (r *cfgExecContext) AddBlock(oc *OriginContext) error {
 }
 
 func (r *cfgExecContext) GetDAGCtx(fow FOWS, gvk *schema.GroupVersionKind, op Operation) *RTDAGCtx {
+	if gvk == nil {
+		return nil
+	}
 	r.m.RLock()
 	defer r.m.RUnlock()
 	switch fow {
@@ -206,48 +249,135 @@ func (r *cfgExecContext) GetFOW(fow FOWS) map[schema.GroupVersionKind]OperationC
 }
 
 func (r *cfgExecContext) GetForGVK() *schema.GroupVersionKind {
-	for gvk := range r.For {
+	for _, gvk := range sortedGVKs(r.For) {
+		gvk := gvk
 		return &gvk
 	}
 	return &schema.GroupVersionKind{}
 }
 
-/*
-func (r *cfgExecContext) AddService(gvk *schema.GroupVersionKind, fn ctrlcfgv1alpha1.Function) error {
-	if _, ok := r.services[*gvk]; ok {
-		return fmt.Errorf("duplicate gvk service entry: %s", gvk.String())
+// AddService adds a service and allocates its port. The port is allocated in
+// the order the services are added, so services need to be added in a
+// deterministic order to get a deterministic port assignment.
+func (r *cfgExecContext) AddService(name string, fn ctrlcfgv1alpha1.Function) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if _, ok := r.services[name]; ok {
+		return fmt.Errorf("duplicate service entry: %s", name)
+	}
+	r.services[name] = &ServiceCtx{
+		Name: name,
+		Port: serviceBasePort + r.serviceIdx,
+		Fn:   fn,
+		GVKs: []schema.GroupVersionKind{},
 	}
-	r.services[*gvk] = ServiceCtx{Fn: fn, Port: 9000 + r.serviceIdx}
 	r.serviceIdx++
 	return nil
 }
 
-func (r *cfgExecContext) GetServices() service.Services {
+// AddServiceGVK registers the output gvk of a service, a gvk can only be
+// served by a single service
+func (r *cfgExecContext) AddServiceGVK(name string, gvk *schema.GroupVersionKind) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	svcCtx, ok := r.services[name]
+	if !ok {
+		return fmt.Errorf("service not found: %s", name)
+	}
+	if svcName, ok := r.serviceGVKs[*gvk]; ok {
+		return fmt.Errorf("gvk %s is claimed by services %s and %s", gvk.String(), svcName, name)
+	}
+	r.serviceGVKs[*gvk] = name
+	svcCtx.GVKs = append(svcCtx.GVKs, *gvk)
+	return nil
+}
+
+func (r *cfgExecContext) GetService(name string) *ServiceCtx {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return r.services[name]
+}
+
+// GetServices returns a map from the output gvk to the service
+func (r *cfgExecContext) GetServices() map[schema.GroupVersionKind]*ServiceCtx {
 	r.m.RLock()
 	defer r.m.RUnlock()
-	s := service.New()
-	for gvk, svcCtx := range r.services {
-		s.AddEntry(gvk, service.ServiceCtx{Port: svcCtx.Port, Fn: svcCtx.Fn})
+	s := make(map[schema.GroupVersionKind]*ServiceCtx, len(r.serviceGVKs))
+	for gvk, name := range r.serviceGVKs {
+		s[gvk] = r.services[name]
 	}
 	return s
 }
-*/
 
 func (r *cfgExecContext) Print() {
+	r.fprint(os.Stdout, r.For)
+}
+
+// Fprint writes the dags of the for and watches and the services to the
+// writer, sorted such that the output is stable
+func (r *cfgExecContext) Fprint(w io.Writer) {
+	r.fprint(w, r.For, r.watch)
+}
+
+// fprint writes the dags of the supplied fors or watches and the services
+func (r *cfgExecContext) fprint(w io.Writer, fows ...map[schema.GroupVersionKind]OperationCtx) {
 	r.m.RLock()
 	defer r.m.RUnlock()
-	fmt.Printf("###### CEC #######\n")
-	for gvk, oc := range r.For {
-		fmt.Printf("gvk: %v\n", gvk)
-
-		for op, dctx := range oc {
-			fmt.Printf("  op: %s, RootVertexName: %s, blockDAGs: %d\n", op, dctx.RootVertexName, len(dctx.BlockDAGs))
-			dctx.DAG.PrintVertices()
-			for rootVertexName, d := range dctx.BlockDAGs {
-				fmt.Printf("!!!!!!! block dag start: vertexName: %s, %s !!!!!!!!!!\n", rootVertexName, d.GetRootVertex())
-				d.PrintVertices()
-				fmt.Printf("!!!!!!! block dag stop : vertexName: %s, %s !!!!!!!!!!\n", rootVertexName, d.GetRootVertex())
+	fmt.Fprintf(w, "###### CEC #######\n")
+	for _, fow := range fows {
+		for _, gvk := range sortedGVKs(fow) {
+			oc := fow[gvk]
+			fmt.Fprintf(w, "gvk: %v\n", gvk)
+
+			for _, op := range sortedKeys(oc) {
+				dctx := oc[op]
+				fmt.Fprintf(w, "  op: %s, RootVertexName: %s, blockDAGs: %d\n", op, dctx.RootVertexName, len(dctx.BlockDAGs))
+				printVertices(w, dctx.DAG)
+				for _, rootVertexName := range sortedKeys(dctx.BlockDAGs) {
+					d := dctx.BlockDAGs[rootVertexName]
+					fmt.Fprintf(w, "!!!!!!! block dag start: vertexName: %s, %s !!!!!!!!!!\n", rootVertexName, d.GetRootVertex())
+					printVertices(w, d)
+					fmt.Fprintf(w, "!!!!!!! block dag stop : vertexName: %s, %s !!!!!!!!!!\n", rootVertexName, d.GetRootVertex())
+				}
+				for _, e := range dctx.GetServiceEdges() {
+					fmt.Fprintf(w, "  service edge: %s/%s -> %s\n", e.Service, e.Output, e.VertexName)
+				}
+			}
+		}
+	}
+	for _, name := range sortedKeys(r.services) {
+		svcCtx := r.services[name]
+		fmt.Fprintf(w, "service: %s, port: %d, gvks: %v\n", name, svcCtx.Port, svcCtx.GVKs)
+	}
+}
+
+// printVertices prints the vertices of the dag sorted by name, the
+// PrintVertices of the dag ranges over maps and has no stable order
+func printVertices(w io.Writer, d rtdag.RuntimeDAG) {
+	fmt.Fprintf(w, "###### RUNTIME DAG output start #######\n")
+	vertices := d.GetVertices()
+	for _, vertexName := range sortedKeys(vertices) {
+		vc, ok := vertices[vertexName].(*rtdag.VertexContext)
+		if !ok {
+			fmt.Fprintf(w, "vertexname: %s wrong context\n", vertexName)
+			continue
+		}
+		up := d.GetUpVertexes(vertexName)
+		down := d.GetDownVertexes(vertexName)
+		sort.Strings(up)
+		sort.Strings(down)
+		fmt.Fprintf(w, "vertexname: %s upVertices: %v, downVertices: %v\n", vertexName, up, down)
+		if vc.Outputs == nil {
+			continue
+		}
+		outputs := vc.Outputs.Get()
+		for _, varName := range sortedKeys(outputs) {
+			oi, ok := outputs[varName].(*output.OutputInfo)
+			if !ok {
+				continue
 			}
+			fmt.Fprintf(w, "  output varName: %s internal: %t conditioned: %t gvk: %v\n", varName, oi.Internal, oi.Conditioned, oi.GVK)
 		}
 	}
+	fmt.Fprintf(w, "###### RUNTIME DAG output stop #######\n")
 }
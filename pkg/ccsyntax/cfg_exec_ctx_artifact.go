@@ -0,0 +1,551 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/fnrunner/fnruntime/pkg/exec/output"
+	"github.com/fnrunner/fnruntime/pkg/exec/rtdag"
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ArtifactAPIVersion is the version of the artifact format, artifacts
+	// with another version are rejected by the loader
+	ArtifactAPIVersion = "fnsyntax.fnrunner.io/v1alpha1"
+	ArtifactKind       = "CompiledControllerConfig"
+)
+
+// Artifact is the serialized form of a parsed ConfigExecutionContext, it
+// can be loaded without parsing the controller config again
+type Artifact struct {
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string `json:"kind" yaml:"kind"`
+	Name       string `json:"name" yaml:"name"`
+	// ConfigHash is the hash of the controller config the artifact is
+	// compiled from, it is used to detect stale artifacts
+	ConfigHash string `json:"configHash" yaml:"configHash"`
+	// Hash is the hash of the content of the artifact, computed with an
+	// empty Hash
+	Hash     string             `json:"hash" yaml:"hash"`
+	For      []*ArtifactGVK     `json:"for,omitempty" yaml:"for,omitempty"`
+	Own      []*ArtifactGVK     `json:"own,omitempty" yaml:"own,omitempty"`
+	Watch    []*ArtifactGVK     `json:"watch,omitempty" yaml:"watch,omitempty"`
+	Services []*ArtifactService `json:"services,omitempty" yaml:"services,omitempty"`
+}
+
+// ArtifactGVK holds the dags of the operations of a for, own or watch
+type ArtifactGVK struct {
+	GVK        schema.GroupVersionKind `json:"gvk" yaml:"gvk"`
+	Operations []*ArtifactOperation    `json:"operations,omitempty" yaml:"operations,omitempty"`
+}
+
+type ArtifactOperation struct {
+	Operation      Operation              `json:"operation" yaml:"operation"`
+	RootVertexName string                 `json:"rootVertexName" yaml:"rootVertexName"`
+	DAG            *ArtifactDAG           `json:"dag" yaml:"dag"`
+	BlockDAGs      []*ArtifactBlockDAG    `json:"blockDAGs,omitempty" yaml:"blockDAGs,omitempty"`
+	ServiceEdges   []*ArtifactServiceEdge `json:"serviceEdges,omitempty" yaml:"serviceEdges,omitempty"`
+}
+
+type ArtifactBlockDAG struct {
+	VertexName string       `json:"vertexName" yaml:"vertexName"`
+	DAG        *ArtifactDAG `json:"dag" yaml:"dag"`
+}
+
+type ArtifactServiceEdge struct {
+	Service    string                  `json:"service" yaml:"service"`
+	Output     string                  `json:"output" yaml:"output"`
+	GVK        schema.GroupVersionKind `json:"gvk" yaml:"gvk"`
+	VertexName string                  `json:"vertexName" yaml:"vertexName"`
+}
+
+// ArtifactDAG holds the vertices of a dag, the edges are stored in the
+// vertices
+type ArtifactDAG struct {
+	Vertices []*ArtifactVertex `json:"vertices,omitempty" yaml:"vertices,omitempty"`
+}
+
+// ArtifactVertex is the serialized form of a rtdag.VertexContext
+type ArtifactVertex struct {
+	Name         string                   `json:"name" yaml:"name"`
+	Kind         rtdag.VertexKind         `json:"kind" yaml:"kind"`
+	Function     ctrlcfgv1alpha1.Function `json:"function" yaml:"function"`
+	References   []string                 `json:"references,omitempty" yaml:"references,omitempty"`
+	Outputs      []*ArtifactOutput        `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	GVKToVarName map[string]string        `json:"gvkToVarName,omitempty" yaml:"gvkToVarName,omitempty"`
+	// Block is true when the vertex has a block dag
+	Block        bool     `json:"block,omitempty" yaml:"block,omitempty"`
+	UpVertices   []string `json:"upVertices,omitempty" yaml:"upVertices,omitempty"`
+	DownVertices []string `json:"downVertices,omitempty" yaml:"downVertices,omitempty"`
+}
+
+type ArtifactOutput struct {
+	VarName     string                   `json:"varName" yaml:"varName"`
+	Internal    bool                     `json:"internal,omitempty" yaml:"internal,omitempty"`
+	Conditioned bool                     `json:"conditioned,omitempty" yaml:"conditioned,omitempty"`
+	GVK         *schema.GroupVersionKind `json:"gvk,omitempty" yaml:"gvk,omitempty"`
+}
+
+type ArtifactService struct {
+	Name     string                    `json:"name" yaml:"name"`
+	Port     int                       `json:"port" yaml:"port"`
+	Function ctrlcfgv1alpha1.Function  `json:"function" yaml:"function"`
+	GVKs     []schema.GroupVersionKind `json:"gvks,omitempty" yaml:"gvks,omitempty"`
+}
+
+// Compile parses the controller config and serializes the resulting config
+// execution context
+func (r *parser) Compile() (*Artifact, []Result) {
+	ceCtx, result := r.Parse()
+	if len(result) != 0 {
+		return nil, result
+	}
+	a, err := NewArtifact(ceCtx, r.cCfg)
+	if err != nil {
+		return nil, []Result{{Error: err.Error()}}
+	}
+	return a, nil
+}
+
+// NewArtifact serializes the config execution context that is parsed from
+// the controller config
+func NewArtifact(ceCtx ConfigExecutionContext, cfg *ctrlcfgv1alpha1.ControllerConfigSpec) (*Artifact, error) {
+	r, ok := ceCtx.(*cfgExecContext)
+	if !ok {
+		return nil, fmt.Errorf("unexpected config execution context, got: %T", ceCtx)
+	}
+	configHash, err := ConfigHash(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.m.RLock()
+	defer r.m.RUnlock()
+	a := &Artifact{
+		APIVersion: ArtifactAPIVersion,
+		Kind:       ArtifactKind,
+		Name:       r.name,
+		ConfigHash: configHash,
+		For:        newArtifactGVKs(r.For),
+		Own:        newArtifactGVKs(r.own),
+		Watch:      newArtifactGVKs(r.watch),
+	}
+	for _, name := range sortedKeys(r.services) {
+		svcCtx := r.services[name]
+		gvks := make([]schema.GroupVersionKind, len(svcCtx.GVKs))
+		copy(gvks, svcCtx.GVKs)
+		a.Services = append(a.Services, &ArtifactService{
+			Name:     svcCtx.Name,
+			Port:     svcCtx.Port,
+			Function: svcCtx.Fn,
+			GVKs:     gvks,
+		})
+	}
+	if a.Hash, err = a.contentHash(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func newArtifactGVKs(fow map[schema.GroupVersionKind]OperationCtx) []*ArtifactGVK {
+	gvks := []*ArtifactGVK{}
+	for _, gvk := range sortedGVKs(fow) {
+		ag := &ArtifactGVK{GVK: gvk}
+		for _, op := range sortedKeys(fow[gvk]) {
+			dctx := fow[gvk][op]
+			ao := &ArtifactOperation{
+				Operation:      op,
+				RootVertexName: dctx.RootVertexName,
+				DAG:            newArtifactDAG(dctx.DAG),
+			}
+			dctx.m.RLock()
+			for _, vertexName := range sortedKeys(dctx.BlockDAGs) {
+				ao.BlockDAGs = append(ao.BlockDAGs, &ArtifactBlockDAG{
+					VertexName: vertexName,
+					DAG:        newArtifactDAG(dctx.BlockDAGs[vertexName]),
+				})
+			}
+			// the service edges are added in the order the vertices are
+			// connected, they are sorted such that the hash is stable
+			edges := make([]*ServiceEdge, len(dctx.ServiceEdges))
+			copy(edges, dctx.ServiceEdges)
+			sort.SliceStable(edges, func(i, j int) bool {
+				if edges[i].VertexName != edges[j].VertexName {
+					return edges[i].VertexName < edges[j].VertexName
+				}
+				if edges[i].Service != edges[j].Service {
+					return edges[i].Service < edges[j].Service
+				}
+				if edges[i].Output != edges[j].Output {
+					return edges[i].Output < edges[j].Output
+				}
+				return edges[i].GVK.String() < edges[j].GVK.String()
+			})
+			for _, e := range edges {
+				ao.ServiceEdges = append(ao.ServiceEdges, &ArtifactServiceEdge{
+					Service:    e.Service,
+					Output:     e.Output,
+					GVK:        e.GVK,
+					VertexName: e.VertexName,
+				})
+			}
+			dctx.m.RUnlock()
+			ag.Operations = append(ag.Operations, ao)
+		}
+		gvks = append(gvks, ag)
+	}
+	return gvks
+}
+
+func newArtifactDAG(d rtdag.RuntimeDAG) *ArtifactDAG {
+	ad := &ArtifactDAG{}
+	vertices := d.GetVertices()
+	for _, vertexName := range sortedKeys(vertices) {
+		av := &ArtifactVertex{
+			Name:         vertexName,
+			UpVertices:   d.GetUpVertexes(vertexName),
+			DownVertices: d.GetDownVertexes(vertexName),
+		}
+		sort.Strings(av.UpVertices)
+		sort.Strings(av.DownVertices)
+		if vc, ok := vertices[vertexName].(*rtdag.VertexContext); ok {
+			av.Kind = vc.Kind
+			av.Function = vc.Function
+			av.References = vc.References
+			av.GVKToVarName = vc.GVKToVarName
+			av.Block = vc.BlockDAG != nil
+			if vc.Outputs != nil {
+				outputs := vc.Outputs.Get()
+				for _, varName := range sortedKeys(outputs) {
+					oi, ok := outputs[varName].(*output.OutputInfo)
+					if !ok {
+						continue
+					}
+					av.Outputs = append(av.Outputs, &ArtifactOutput{
+						VarName:     varName,
+						Internal:    oi.Internal,
+						Conditioned: oi.Conditioned,
+						GVK:         oi.GVK,
+					})
+				}
+			}
+		}
+		ad.Vertices = append(ad.Vertices, av)
+	}
+	return ad
+}
+
+// ConfigHash returns the hash of the controller config an artifact is
+// compiled from
+func ConfigHash(cfg *ctrlcfgv1alpha1.ControllerConfigSpec) (string, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return hash(b), nil
+}
+
+func hash(b []byte) string {
+	h := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(h[:])
+}
+
+// contentHash returns the hash of the artifact without its Hash
+func (r *Artifact) contentHash() (string, error) {
+	a := *r
+	a.Hash = ""
+	b, err := json.Marshal(&a)
+	if err != nil {
+		return "", err
+	}
+	return hash(b), nil
+}
+
+func (r *Artifact) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func (r *Artifact) YAML() ([]byte, error) {
+	return yaml.Marshal(r)
+}
+
+// LoadArtifact reads an artifact in json or yaml format and validates its
+// version, its content hash and that its edges and block dags refer to
+// vertices of the artifact
+func LoadArtifact(b []byte) (*Artifact, error) {
+	a := &Artifact{}
+	if err := yaml.Unmarshal(b, a); err != nil {
+		return nil, err
+	}
+	if a.APIVersion != ArtifactAPIVersion || a.Kind != ArtifactKind {
+		return nil, fmt.Errorf("unsupported artifact %s %s, supported: %s %s", a.APIVersion, a.Kind, ArtifactAPIVersion, ArtifactKind)
+	}
+	h, err := a.contentHash()
+	if err != nil {
+		return nil, err
+	}
+	if h != a.Hash {
+		return nil, fmt.Errorf("artifact hash mismatch, got: %s, want: %s", a.Hash, h)
+	}
+	// the hash does not protect against an artifact that is edited and
+	// hashed again
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// validate checks that the edges, the block dags and the service edges of
+// the artifact refer to vertices and services of the artifact
+func (r *Artifact) validate() error {
+	services := map[string]bool{}
+	for _, svc := range r.Services {
+		services[svc.Name] = true
+	}
+	for _, ag := range append(append(append([]*ArtifactGVK{}, r.For...), r.Own...), r.Watch...) {
+		for _, ao := range ag.Operations {
+			if err := ao.validate(services); err != nil {
+				return fmt.Errorf("invalid artifact %s %s: %s", ag.GVK.String(), ao.Operation, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+func (r *ArtifactOperation) validate(services map[string]bool) error {
+	if r.DAG == nil {
+		return fmt.Errorf("dag not found for %s", r.RootVertexName)
+	}
+	// vertices holds the vertices of all the dags of the operation, the
+	// vertex of a block dag is in the root dag or in another block dag
+	vertices := map[string]bool{}
+	dags := []*ArtifactDAG{r.DAG}
+	for _, ab := range r.BlockDAGs {
+		if ab.DAG == nil {
+			return fmt.Errorf("block dag not found for %s", ab.VertexName)
+		}
+		dags = append(dags, ab.DAG)
+	}
+	for _, ad := range dags {
+		if err := ad.validate(); err != nil {
+			return err
+		}
+		for _, av := range ad.Vertices {
+			vertices[av.Name] = true
+		}
+	}
+	for _, ab := range r.BlockDAGs {
+		if !vertices[ab.VertexName] {
+			return fmt.Errorf("block dag of unknown vertex %s", ab.VertexName)
+		}
+	}
+	for _, e := range r.ServiceEdges {
+		if !vertices[e.VertexName] {
+			return fmt.Errorf("service edge of unknown vertex %s", e.VertexName)
+		}
+		if !services[e.Service] {
+			return fmt.Errorf("service edge of vertex %s to unknown service %s", e.VertexName, e.Service)
+		}
+	}
+	return nil
+}
+
+// validate checks that the vertices are unique and that every edge connects
+// two vertices of the dag in both directions
+func (r *ArtifactDAG) validate() error {
+	vertices := map[string]*ArtifactVertex{}
+	for _, av := range r.Vertices {
+		if _, ok := vertices[av.Name]; ok {
+			return fmt.Errorf("duplicate vertex %s", av.Name)
+		}
+		vertices[av.Name] = av
+	}
+	for _, av := range r.Vertices {
+		for _, down := range av.DownVertices {
+			dv, ok := vertices[down]
+			if !ok {
+				return fmt.Errorf("edge from %s to unknown vertex %s", av.Name, down)
+			}
+			if !containsString(dv.UpVertices, av.Name) {
+				return fmt.Errorf("edge from %s to %s has no up edge", av.Name, down)
+			}
+		}
+		for _, up := range av.UpVertices {
+			uv, ok := vertices[up]
+			if !ok {
+				return fmt.Errorf("edge to %s from unknown vertex %s", av.Name, up)
+			}
+			if !containsString(uv.DownVertices, av.Name) {
+				return fmt.Errorf("edge from %s to %s has no down edge", up, av.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Stale returns true if the artifact is not compiled from the controller
+// config
+func (r *Artifact) Stale(cfg *ctrlcfgv1alpha1.ControllerConfigSpec) (bool, error) {
+	h, err := ConfigHash(cfg)
+	if err != nil {
+		return false, err
+	}
+	return h != r.ConfigHash, nil
+}
+
+// ConfigExecutionContext rebuilds the config execution context from the
+// artifact
+func (r *Artifact) ConfigExecutionContext() (ConfigExecutionContext, error) {
+	cec := NewConfigExecutionContext(r.Name).(*cfgExecContext)
+	for _, fow := range []struct {
+		gvks []*ArtifactGVK
+		m    map[schema.GroupVersionKind]OperationCtx
+	}{
+		{gvks: r.For, m: cec.For},
+		{gvks: r.Own, m: cec.own},
+		{gvks: r.Watch, m: cec.watch},
+	} {
+		for _, ag := range fow.gvks {
+			if _, ok := fow.m[ag.GVK]; ok {
+				return nil, fmt.Errorf("duplicate gvk entry: %s", ag.GVK.String())
+			}
+			opCtx := OperationCtx{}
+			for _, ao := range ag.Operations {
+				dctx, err := ao.rtDAGCtx()
+				if err != nil {
+					return nil, err
+				}
+				opCtx[ao.Operation] = dctx
+			}
+			fow.m[ag.GVK] = opCtx
+		}
+	}
+	for _, svc := range r.Services {
+		if _, ok := cec.services[svc.Name]; ok {
+			return nil, fmt.Errorf("duplicate service entry: %s", svc.Name)
+		}
+		svcCtx := &ServiceCtx{
+			Name: svc.Name,
+			Port: svc.Port,
+			Fn:   svc.Function,
+			GVKs: []schema.GroupVersionKind{},
+		}
+		cec.services[svc.Name] = svcCtx
+		cec.serviceIdx++
+		for _, gvk := range svc.GVKs {
+			if err := cec.AddServiceGVK(svc.Name, &gvk); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return cec, nil
+}
+
+func (r *ArtifactOperation) rtDAGCtx() (*RTDAGCtx, error) {
+	if r.DAG == nil {
+		return nil, fmt.Errorf("dag not found for %s", r.RootVertexName)
+	}
+	dctx := &RTDAGCtx{
+		RootVertexName: r.RootVertexName,
+		BlockDAGs:      map[string]rtdag.RuntimeDAG{},
+	}
+	for _, ab := range r.BlockDAGs {
+		if ab.DAG == nil {
+			return nil, fmt.Errorf("block dag not found for %s", ab.VertexName)
+		}
+		d, err := ab.DAG.runtimeDAG(nil)
+		if err != nil {
+			return nil, err
+		}
+		dctx.BlockDAGs[ab.VertexName] = d
+	}
+	d, err := r.DAG.runtimeDAG(dctx.BlockDAGs)
+	if err != nil {
+		return nil, err
+	}
+	dctx.DAG = d
+	for _, e := range r.ServiceEdges {
+		dctx.ServiceEdges = append(dctx.ServiceEdges, &ServiceEdge{
+			Service:    e.Service,
+			Output:     e.Output,
+			GVK:        e.GVK,
+			VertexName: e.VertexName,
+		})
+	}
+	return dctx, nil
+}
+
+// runtimeDAG rebuilds the dag, the vertices with a block get the block dag
+// with the same name
+func (r *ArtifactDAG) runtimeDAG(blockDAGs map[string]rtdag.RuntimeDAG) (rtdag.RuntimeDAG, error) {
+	d := rtdag.New()
+	for _, av := range r.Vertices {
+		outputs := output.New()
+		for _, ao := range av.Outputs {
+			outputs.AddEntry(ao.VarName, &output.OutputInfo{
+				Internal:    ao.Internal,
+				Conditioned: ao.Conditioned,
+				GVK:         ao.GVK,
+			})
+		}
+		references := av.References
+		if references == nil {
+			references = []string{}
+		}
+		vc := &rtdag.VertexContext{
+			VertexName:   av.Name,
+			Kind:         av.Kind,
+			Function:     av.Function,
+			References:   references,
+			Outputs:      outputs,
+			GVKToVarName: av.GVKToVarName,
+		}
+		if av.Block {
+			blockDAG, ok := blockDAGs[av.Name]
+			if !ok {
+				return nil, fmt.Errorf("block dag not found for %s", av.Name)
+			}
+			vc.BlockDAG = blockDAG
+		}
+		if err := d.AddVertex(av.Name, vc); err != nil {
+			return nil, err
+		}
+		for _, up := range av.UpVertices {
+			d.AddUpEdge(av.Name, up)
+		}
+		for _, down := range av.DownVertices {
+			d.AddDownEdge(av.Name, down)
+		}
+	}
+	return d, nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fnrunner/fnruntime/pkg/exec/rtdag"
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+func TestArtifact(t *testing.T) {
+	for _, name := range []string{"upf", "topo4"} {
+		t.Run(name, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join("..", "..", "examples", name+".yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+			if err := yaml.Unmarshal(b, cfg); err != nil {
+				t.Fatal(err)
+			}
+			p, result := NewParser(name, cfg)
+			if len(result) != 0 {
+				t.Fatal(result)
+			}
+			ceCtx, result := p.Parse()
+			if len(result) != 0 {
+				t.Fatal(result)
+			}
+			a, result := p.Compile()
+			if len(result) != 0 {
+				t.Fatal(result)
+			}
+
+			for _, marshal := range []func() ([]byte, error){a.JSON, a.YAML} {
+				b, err := marshal()
+				if err != nil {
+					t.Fatal(err)
+				}
+				loaded, err := LoadArtifact(b)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if loaded.Hash != a.Hash {
+					t.Errorf("hash changed after loading, got: %s, want: %s", loaded.Hash, a.Hash)
+				}
+				stale, err := loaded.Stale(cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stale {
+					t.Errorf("artifact is stale for the config it is compiled from")
+				}
+				loadedCtx, err := loaded.ConfigExecutionContext()
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := &bytes.Buffer{}
+				ceCtx.Fprint(want)
+				got := &bytes.Buffer{}
+				loadedCtx.Fprint(got)
+				if got.String() != want.String() {
+					t.Errorf("loaded config execution context differs\ngot:\n%s\nwant:\n%s", got, want)
+				}
+				// the loaded context compiles to the same artifact
+				reloaded, err := NewArtifact(loadedCtx, cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if reloaded.Hash != a.Hash {
+					t.Errorf("hash of the loaded context, got: %s, want: %s", reloaded.Hash, a.Hash)
+				}
+			}
+
+			// a changed config makes the artifact stale
+			cfg.Pipelines = cfg.Pipelines[1:]
+			if stale, err := a.Stale(cfg); err != nil || !stale {
+				t.Errorf("artifact is not stale for a changed config, err: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadArtifactErrors(t *testing.T) {
+	a, err := NewArtifact(NewConfigExecutionContext("test"), &ctrlcfgv1alpha1.ControllerConfigSpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := a.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tc := range map[string]struct {
+		artifact string
+		err      string
+	}{
+		"version": {
+			artifact: strings.Replace(string(b), ArtifactAPIVersion, "fnsyntax.fnrunner.io/v0", 1),
+			err:      "unsupported artifact",
+		},
+		"content": {
+			artifact: strings.Replace(string(b), `"name": "test"`, `"name": "changed"`, 1),
+			err:      "artifact hash mismatch",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadArtifact([]byte(tc.artifact)); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got error %v, want %s", err, tc.err)
+			}
+		})
+	}
+}
+
+// validArtifact returns an artifact with a block dag and a service edge
+func validArtifact() *Artifact {
+	return &Artifact{
+		APIVersion: ArtifactAPIVersion,
+		Kind:       ArtifactKind,
+		Name:       "test",
+		For: []*ArtifactGVK{{
+			GVK: schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Operations: []*ArtifactOperation{{
+				Operation:      OperationApply,
+				RootVertexName: "pod",
+				DAG: &ArtifactDAG{Vertices: []*ArtifactVertex{
+					{Name: "pod", DownVertices: []string{"block"}},
+					{Name: "block", Block: true, UpVertices: []string{"pod"}},
+				}},
+				BlockDAGs: []*ArtifactBlockDAG{{
+					VertexName: "block",
+					DAG:        &ArtifactDAG{Vertices: []*ArtifactVertex{{Name: "task"}}},
+				}},
+				ServiceEdges: []*ArtifactServiceEdge{{Service: "svc", Output: "out", VertexName: "task"}},
+			}},
+		}},
+		Services: []*ArtifactService{{Name: "svc", Port: 8000}},
+	}
+}
+
+func TestLoadTamperedArtifact(t *testing.T) {
+	for name, tc := range map[string]struct {
+		tamper func(a *Artifact)
+		err    string
+	}{
+		"Valid": {
+			tamper: func(a *Artifact) {},
+		},
+		"DownEdgeToUnknownVertex": {
+			tamper: func(a *Artifact) {
+				a.For[0].Operations[0].DAG.Vertices[0].DownVertices = append(a.For[0].Operations[0].DAG.Vertices[0].DownVertices, "ghost")
+			},
+			err: "edge from pod to unknown vertex ghost",
+		},
+		"UpEdgeFromUnknownVertex": {
+			tamper: func(a *Artifact) {
+				a.For[0].Operations[0].DAG.Vertices[1].UpVertices = append(a.For[0].Operations[0].DAG.Vertices[1].UpVertices, "ghost")
+			},
+			err: "edge to block from unknown vertex ghost",
+		},
+		"OneSidedEdge": {
+			tamper: func(a *Artifact) {
+				a.For[0].Operations[0].DAG.Vertices[1].UpVertices = nil
+			},
+			err: "edge from pod to block has no up edge",
+		},
+		"DuplicateVertex": {
+			tamper: func(a *Artifact) {
+				a.For[0].Operations[0].DAG.Vertices = append(a.For[0].Operations[0].DAG.Vertices, &ArtifactVertex{Name: "pod"})
+			},
+			err: "duplicate vertex pod",
+		},
+		"BlockDAGOfUnknownVertex": {
+			tamper: func(a *Artifact) {
+				a.For[0].Operations[0].BlockDAGs[0].VertexName = "ghost"
+			},
+			err: "block dag of unknown vertex ghost",
+		},
+		"ServiceEdgeOfUnknownVertex": {
+			tamper: func(a *Artifact) {
+				a.For[0].Operations[0].ServiceEdges[0].VertexName = "ghost"
+			},
+			err: "service edge of unknown vertex ghost",
+		},
+		"ServiceEdgeToUnknownService": {
+			tamper: func(a *Artifact) {
+				a.Services = nil
+			},
+			err: "service edge of vertex task to unknown service svc",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := validArtifact()
+			tc.tamper(a)
+			// the tampered artifact is hashed again, such that only the
+			// validation can reject it
+			var err error
+			if a.Hash, err = a.contentHash(); err != nil {
+				t.Fatal(err)
+			}
+			b, err := a.JSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = LoadArtifact(b)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got error %v, want %s", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestArtifactServiceEdgeOrder(t *testing.T) {
+	edges := []*ServiceEdge{
+		{Service: "b", Output: "out", VertexName: "task"},
+		{Service: "a", Output: "out", VertexName: "task"},
+		{Service: "a", Output: "out", VertexName: "other"},
+	}
+	hashes := map[string]bool{}
+	for _, order := range [][]int{{0, 1, 2}, {2, 1, 0}, {1, 2, 0}} {
+		dctx := &RTDAGCtx{DAG: rtdag.New(), RootVertexName: "pod", BlockDAGs: map[string]rtdag.RuntimeDAG{}}
+		for _, i := range order {
+			dctx.AddServiceEdge(edges[i])
+		}
+		ceCtx := NewConfigExecutionContext("test").(*cfgExecContext)
+		ceCtx.For[schema.GroupVersionKind{Version: "v1", Kind: "Pod"}] = OperationCtx{OperationApply: dctx}
+		a, err := NewArtifact(ceCtx, &ctrlcfgv1alpha1.ControllerConfigSpec{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes[a.Hash] = true
+	}
+	if len(hashes) != 1 {
+		t.Errorf("got %d hashes for the same service edges in different orders, want 1", len(hashes))
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cfggen generates synthetic controller configs to benchmark and
+// scale test the parser
+package cfggen
+
+import (
+	"fmt"
+	"math/rand"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	group   = "gen.fnrunner.io/v1alpha1"
+	forName = "root"
+	// kinds is the number of distinct resource kinds the functions query
+	kinds = 10
+)
+
+// Config defines the shape of the generated controller config
+type Config struct {
+	// Pipelines is the number of pipelines, the first 2 are the apply and
+	// delete pipeline of the for, every other pipeline is the apply pipeline
+	// of a watch. A value below 2 generates the 2 pipelines of the for.
+	Pipelines int
+	// Vertices is the number of vertices per pipeline
+	Vertices int
+	// BlockEvery makes every nth vertex a block, alternating between a
+	// condition block and a range. A zero value generates no blocks.
+	BlockEvery int
+	// References is the number of earlier vertices every vertex references
+	References int
+	// Seed seeds the selection of the referenced vertices
+	Seed int64
+}
+
+// Generate returns a valid controller config with the shape of the config.
+// The vertices rotate over the query, jq, map, gotemplate and container
+// function types. The same config generates the same controller config.
+func Generate(cfg Config) *ctrlcfgv1alpha1.ControllerConfigSpec {
+	g := &generator{
+		cfg: cfg,
+		rnd: rand.New(rand.NewSource(cfg.Seed)),
+	}
+	pipelines := cfg.Pipelines
+	if pipelines < 2 {
+		pipelines = 2
+	}
+	spec := &ctrlcfgv1alpha1.ControllerConfigSpec{
+		For: map[string]*ctrlcfgv1alpha1.GvkObject{
+			forName: {
+				Resource:          resource("Root"),
+				ApplyPipelineRef:  pipelineName(0),
+				DeletePipelineRef: pipelineName(1),
+			},
+		},
+		Watch:     map[string]*ctrlcfgv1alpha1.GvkObject{},
+		Pipelines: make([]*ctrlcfgv1alpha1.Pipeline, 0, pipelines),
+	}
+	spec.Pipelines = append(spec.Pipelines, g.pipeline(0, forName), g.pipeline(1, forName))
+	for p := 2; p < pipelines; p++ {
+		watchName := fmt.Sprintf("watch%d", p)
+		spec.Watch[watchName] = &ctrlcfgv1alpha1.GvkObject{
+			Resource:         resource(fmt.Sprintf("Watch%d", p)),
+			ApplyPipelineRef: pipelineName(p),
+		}
+		spec.Pipelines = append(spec.Pipelines, g.pipeline(p, watchName))
+	}
+	return spec
+}
+
+type generator struct {
+	cfg Config
+	rnd *rand.Rand
+}
+
+// pipeline generates the tasks of a pipeline, the vertex names are unique
+// per pipeline since the apply and delete pipeline share the variables of the
+// for
+func (r *generator) pipeline(p int, rootVertexName string) *ctrlcfgv1alpha1.Pipeline {
+	pipeline := &ctrlcfgv1alpha1.Pipeline{
+		Name:  pipelineName(p),
+		Tasks: make(map[string]*ctrlcfgv1alpha1.FunctionElement, r.cfg.Vertices),
+	}
+	// the variables the functions can reference, the variables of the
+	// functions in a block are not referenced
+	vertices := []string{rootVertexName}
+	for i := 0; i < r.cfg.Vertices; i++ {
+		vertexName := fmt.Sprintf("p%dv%d", p, i)
+		vars := r.vars(vertices)
+		var fe *ctrlcfgv1alpha1.FunctionElement
+		varName := vertexName
+		switch {
+		case r.cfg.BlockEvery > 0 && i%r.cfg.BlockEvery == r.cfg.BlockEvery-1 && (i/r.cfg.BlockEvery)%2 == 0:
+			fe = r.conditionBlock(vertexName, vertices, vars)
+		case r.cfg.BlockEvery > 0 && i%r.cfg.BlockEvery == r.cfg.BlockEvery-1:
+			fe = &ctrlcfgv1alpha1.FunctionElement{Function: rangeFunction(rootVertexName, vars)}
+		default:
+			fe = &ctrlcfgv1alpha1.FunctionElement{Function: function(i, vertexName, rootVertexName, vars)}
+			// the result of a function with output is stored in its outputs
+			for outputName := range fe.Output {
+				varName = outputName
+			}
+		}
+		pipeline.Tasks[vertexName] = fe
+		vertices = append(vertices, varName)
+	}
+	return pipeline
+}
+
+// vars returns local variables referencing randomly selected variables
+func (r *generator) vars(vertices []string) map[string]string {
+	if r.cfg.References == 0 {
+		return nil
+	}
+	vars := make(map[string]string, r.cfg.References)
+	for k := 0; k < r.cfg.References; k++ {
+		vars[fmt.Sprintf("ref%d", k)] = fmt.Sprintf("$%s", vertices[r.rnd.Intn(len(vertices))])
+	}
+	return vars
+}
+
+// conditionBlock generates a block with a condition on the result of an
+// earlier vertex, holding a jq function and a range
+func (r *generator) conditionBlock(vertexName string, vertices []string, vars map[string]string) *ctrlcfgv1alpha1.FunctionElement {
+	rootVertexName := vertices[0]
+	return &ctrlcfgv1alpha1.FunctionElement{
+		Function: ctrlcfgv1alpha1.Function{
+			Type: ctrlcfgv1alpha1.BlockType,
+			Vars: vars,
+			Block: ctrlcfgv1alpha1.Block{
+				Condition: &ctrlcfgv1alpha1.ConditionExpression{
+					Expression: fmt.Sprintf("$%s | length != 0", vertices[len(vertices)-1]),
+				},
+			},
+		},
+		FunctionBlock: map[string]*ctrlcfgv1alpha1.FunctionElement{
+			vertexName + "jq":    {Function: function(1, vertexName+"jq", rootVertexName, r.vars(vertices))},
+			vertexName + "range": {Function: rangeFunction(rootVertexName, r.vars(vertices))},
+		},
+	}
+}
+
+// function generates a function of the function type at the index in the
+// rotation, the input references the first local variable or the root vertex
+func function(i int, vertexName, rootVertexName string, vars map[string]string) ctrlcfgv1alpha1.Function {
+	ref := "$" + rootVertexName
+	if len(vars) != 0 {
+		ref = "$ref0"
+	}
+	switch i % 5 {
+	case 0:
+		return ctrlcfgv1alpha1.Function{
+			Type: ctrlcfgv1alpha1.QueryType,
+			Vars: vars,
+			Input: &ctrlcfgv1alpha1.Input{
+				Resource: resource(fmt.Sprintf("Resource%d", i%kinds)),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"name": ref + ".metadata.name"},
+				},
+			},
+		}
+	case 1:
+		return ctrlcfgv1alpha1.Function{
+			Type: ctrlcfgv1alpha1.JQType,
+			Vars: vars,
+			Input: &ctrlcfgv1alpha1.Input{
+				Expression: ref + " | .spec.properties | select(. != null)",
+			},
+		}
+	case 2:
+		return ctrlcfgv1alpha1.Function{
+			Type: ctrlcfgv1alpha1.MapType,
+			Vars: vars,
+			Input: &ctrlcfgv1alpha1.Input{
+				Key:   ref + ".metadata.name",
+				Value: ref + ".spec",
+			},
+		}
+	case 3:
+		return ctrlcfgv1alpha1.Function{
+			Type: ctrlcfgv1alpha1.GoTemplateType,
+			Vars: vars,
+			Input: &ctrlcfgv1alpha1.Input{
+				Resource: resource(fmt.Sprintf("Resource%d", i%kinds)),
+			},
+		}
+	default:
+		return ctrlcfgv1alpha1.Function{
+			Type: ctrlcfgv1alpha1.ContainerType,
+			Vars: vars,
+			Executor: ctrlcfgv1alpha1.Executor{
+				Image: fmt.Sprintf("example.com/fn/fn%d:v1.0.0", i%kinds),
+			},
+			Output: map[string]*ctrlcfgv1alpha1.Output{
+				vertexName + "out": {
+					Resource: resource(fmt.Sprintf("Output%d", i%kinds)),
+				},
+			},
+		}
+	}
+}
+
+// rangeFunction generates a map function that ranges over the items of the
+// root vertex
+func rangeFunction(rootVertexName string, vars map[string]string) ctrlcfgv1alpha1.Function {
+	return ctrlcfgv1alpha1.Function{
+		Type: ctrlcfgv1alpha1.MapType,
+		Vars: vars,
+		Block: ctrlcfgv1alpha1.Block{
+			Range: &ctrlcfgv1alpha1.RangeValue{
+				Value: fmt.Sprintf("$%s | .spec.items | .[]", rootVertexName),
+			},
+		},
+		Input: &ctrlcfgv1alpha1.Input{
+			Key:   "$VALUE.name",
+			Value: "$VALUE",
+		},
+	}
+}
+
+func pipelineName(p int) string {
+	return fmt.Sprintf("pipeline%d", p)
+}
+
+func resource(kind string) runtime.RawExtension {
+	return runtime.RawExtension{
+		Raw: []byte(fmt.Sprintf(`{"apiVersion":%q,"kind":%q}`, group, kind)),
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cfggen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	cfg := Config{Pipelines: 3, Vertices: 20, BlockEvery: 5, References: 3, Seed: 1}
+	spec := Generate(cfg)
+	if len(spec.GetFors()) != 1 {
+		t.Errorf("expected 1 for, got %d", len(spec.GetFors()))
+	}
+	if len(spec.GetWatches()) != 1 {
+		t.Errorf("expected 1 watch, got %d", len(spec.GetWatches()))
+	}
+	if len(spec.Pipelines) != cfg.Pipelines {
+		t.Fatalf("expected %d pipelines, got %d", cfg.Pipelines, len(spec.Pipelines))
+	}
+	for _, p := range spec.Pipelines {
+		if len(p.Tasks) != cfg.Vertices {
+			t.Errorf("pipeline %s: expected %d vertices, got %d", p.Name, cfg.Vertices, len(p.Tasks))
+		}
+	}
+	if !reflect.DeepEqual(spec, Generate(cfg)) {
+		t.Error("expected the same config to generate the same controller config")
+	}
+}
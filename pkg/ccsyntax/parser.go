@@ -17,20 +17,38 @@ limitations under the License.
 package ccsyntax
 
 import (
+	"context"
+	"fmt"
+
 	fnrunv1alpha1 "github.com/fnrunner/fnruntime/apis/fnrun/v1alpha1"
 	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
 	"github.com/go-logr/logr"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 type Parser interface {
 	GetExternalResources() ([]*schema.GroupVersionKind, []Result)
+	GetExternalResourceUsages() ([]*ExternalResource, []Result)
 	Parse() (ConfigExecutionContext, []Result)
+	ParseContext(ctx context.Context) (ConfigExecutionContext, []Result)
+	ParseAll() (ConfigExecutionContext, []Result)
 	GetImages() []*fnrunv1alpha1.Image
+	GetImageInventory() (*ImageInventory, []Result)
+	ValidateImages(policy *ImagePolicy) []Result
+	ValidateConfigs(registry ConfigSchemaRegistry) []Result
+	LintExec(policy *ExecPolicy) []Result
+	Walk(v Visitor) error
+	ValidateOwnership() []Result
+	GetPolicyRules() ([]rbacv1.PolicyRule, []Result)
+	GetClusterRole(name string) (*rbacv1.ClusterRole, []Result)
+	GetRole(name, namespace string) (*rbacv1.Role, []Result)
+	GetServiceManifests(namespace string) ([]*ServiceManifest, []Result)
+	Compile() (*Artifact, []Result)
 }
 
-func NewParser(controllerName string, cfg *ctrlcfgv1alpha1.ControllerConfigSpec) (Parser, []Result) {
+func NewParser(controllerName string, cfg *ctrlcfgv1alpha1.ControllerConfigSpec, opts ...Option) (Parser, []Result) {
 	p := &parser{
 		controllerName: controllerName,
 		cCfg:           cfg,
@@ -38,55 +56,144 @@ func NewParser(controllerName string, cfg *ctrlcfgv1alpha1.ControllerConfigSpec)
 		//output: map[string]string{},
 		l: ctrl.Log.WithName("parser"),
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.rootVertexName = cfg.GetRootVertexName()
+	// a config that exceeds the limits is not decoded nor validated, the
+	// limits are checked once and the results are kept for parse
+	p.limitResults = DeduplicateResults(checkLimits(cfg, p.limits))
+	if len(p.limitResults) != 0 {
+		// the other methods of the parser see an empty config
+		p.ir = newConfigIR(&ctrlcfgv1alpha1.ControllerConfigSpec{})
+		return p, p.limitResults
+	}
+	// the intermediate representation is built once and used by all phases
+	p.ir = newLimitedConfigIR(cfg, p.limits)
 	// add the callback function to record validation results results
 	result := p.ValidateSyntax()
-	p.rootVertexName = cfg.GetRootVertexName()
 
-	return p, result
+	return p, DeduplicateResults(result)
 }
 
 type parser struct {
 	controllerName string
 	cCfg           *ctrlcfgv1alpha1.ControllerConfigSpec
 	rootVertexName string
+	ir             *configIR
 	l              logr.Logger
+	limits         Limits
+	// limitResults holds the limits the config exceeds
+	limitResults []Result
+	keepGoing    bool
 }
 
 func (r *parser) Parse() (ConfigExecutionContext, []Result) {
+	return r.parse(context.Background(), r.keepGoing)
+}
+
+// ParseContext parses the config like Parse and stops when the context is done
+func (r *parser) ParseContext(ctx context.Context) (ConfigExecutionContext, []Result) {
+	return r.parse(ctx, r.keepGoing)
+}
+
+// ParseAll parses the config like Parse, but continues with the next phases
+// when a phase fails. The vertices that failed are skipped in the next phases
+// and the results of all phases are returned with the partially built config
+// execution context.
+func (r *parser) ParseAll() (ConfigExecutionContext, []Result) {
+	return r.parse(context.Background(), true)
+}
+
+func (r *parser) parse(ctx context.Context, keepGoing bool) (ConfigExecutionContext, []Result) {
+	// a config that exceeds the limits is not parsed
+	if len(r.limitResults) != 0 {
+		return nil, r.limitResults
+	}
+	failed := newFailedVertices()
+	results := []Result{}
 	// initialize the config execution context
 	// for each for and watch a new dag is created
-	ceCtx, gvar, result := r.init()
+	ceCtx, gvar, result := r.init(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, canceledResults(append(results, result...), err)
+	}
 	if len(result) != 0 {
-		return nil, result
+		if !keepGoing {
+			return nil, DeduplicateResults(result)
+		}
+		r.l.Info("init failed")
+		results = append(results, result...)
+		failed.add(result)
 	}
 	// resolves the dependencies in the dag
 	// step1. check if all dependencies resolve
 	// step2. add the dependencies in the dag
-	result = r.populate(ceCtx, gvar)
+	result = r.populate(ctx, ceCtx, gvar, failed)
+	if err := ctx.Err(); err != nil {
+		return nil, canceledResults(append(results, result...), err)
+	}
 	if len(result) != 0 {
 		r.l.Info("populate failed")
-		return nil, result
+		if !keepGoing {
+			return nil, DeduplicateResults(result)
+		}
+		results = append(results, result...)
+		failed.add(result)
 	}
 	//fmt.Println("propulate succeded")
-	result = r.resolve(ceCtx, gvar)
+	result = r.resolve(ctx, ceCtx, gvar, failed)
+	if err := ctx.Err(); err != nil {
+		return nil, canceledResults(append(results, result...), err)
+	}
 	if len(result) != 0 {
 		r.l.Info("resolve failed")
-		return nil, result
+		if !keepGoing {
+			return nil, DeduplicateResults(result)
+		}
+		results = append(results, result...)
+		failed.add(result)
 	}
 	//fmt.Println("resolve succeded")
-	result = r.connect(ceCtx, gvar)
+	result = r.connect(ctx, ceCtx, gvar, failed)
+	if err := ctx.Err(); err != nil {
+		return nil, canceledResults(append(results, result...), err)
+	}
 	if len(result) != 0 {
 		r.l.Info("connect failed")
-		return nil, result
+		if !keepGoing {
+			return nil, DeduplicateResults(result)
+		}
+		results = append(results, result...)
+	}
+	// the transitive reduction does not terminate on a cycle
+	if result := r.checkCycles(ceCtx); len(result) != 0 {
+		return nil, DeduplicateResults(append(results, result...))
+	}
+	// the transitive reduction walks all the paths of the dags, a config
+	// with more paths than the limit is not reduced
+	if result := r.checkPaths(ceCtx); len(result) != 0 {
+		return nil, DeduplicateResults(append(results, result...))
 	}
 	// optimizes the dependncy graph based on transit reduction
 	// techniques
 	r.transitivereduction(ceCtx)
 
 	//ceCtx.Print()
+	if len(results) != 0 {
+		return ceCtx, DeduplicateResults(results)
+	}
 	return ceCtx, nil
 }
 
+// canceledResults adds the reason of the cancellation to the results
+func canceledResults(results []Result, err error) []Result {
+	return DeduplicateResults(append(results, Result{
+		Error: fmt.Errorf("parse canceled: %s", err.Error()).Error(),
+		Code:  CodeCanceled,
+	}))
+}
+
 func (r *parser) transitivereduction(ceCtx ConfigExecutionContext) {
 	// transitive reduction for For dag
 	for _, od := range ceCtx.GetFOW(FOWFor) {
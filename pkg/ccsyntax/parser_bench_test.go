@@ -0,0 +1,208 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/fnrunner/fnsyntax/pkg/ccsyntax/cfggen"
+)
+
+// scales are the shapes of the generated configs for the benchmarks
+var scales = []cfggen.Config{
+	{Pipelines: 2, Vertices: 100, BlockEvery: 10, References: 2},
+	{Pipelines: 2, Vertices: 500, BlockEvery: 10, References: 2},
+	{Pipelines: 4, Vertices: 200, BlockEvery: 10, References: 4},
+	{Pipelines: 2, Vertices: 1000, BlockEvery: 10, References: 2},
+	// a dense reference graph, the transitive reduction of the dag walks all
+	// paths and takes minutes from 8 references on 100 vertices
+	{Pipelines: 2, Vertices: 50, BlockEvery: 10, References: 8},
+}
+
+// denseScale has a block on every second vertex and 345M paths in a dag.
+// Depending on the order the transitive reduction visits the vertices, a
+// parse takes from 1s to more than 30s. It is only parsed in the benchmarks,
+// the tests check that the MaxPaths rejects it.
+var denseScale = cfggen.Config{Pipelines: 3, Vertices: 40, BlockEvery: 2, References: 16}
+
+// generatedLimits admits all the scales and rejects the denseScale, the
+// scales have up to 23M paths in a dag
+var generatedLimits = Limits{MaxPaths: 100000000}
+
+func scaleName(cfg cfggen.Config) string {
+	return fmt.Sprintf("pipelines-%d/vertices-%d/refs-%d", cfg.Pipelines, cfg.Vertices, cfg.References)
+}
+
+func newBenchmarkParser(b *testing.B, cfg cfggen.Config) *parser {
+	p, result := NewParser("bench", cfggen.Generate(cfg))
+	if len(result) != 0 {
+		b.Fatal(result)
+	}
+	return p.(*parser)
+}
+
+func TestGeneratedConfigs(t *testing.T) {
+	for _, cfg := range append(scales, cfggen.Config{Vertices: 1}) {
+		t.Run(scaleName(cfg), func(t *testing.T) {
+			p, result := NewParser("scale", cfggen.Generate(cfg), WithLimits(generatedLimits))
+			if len(result) != 0 {
+				t.Fatalf("unexpected validation results: %v", result)
+			}
+			ceCtx, result := p.Parse()
+			if len(result) != 0 {
+				t.Fatalf("unexpected parse results: %v", result)
+			}
+			if ceCtx == nil {
+				t.Fatal("expected a config execution context")
+			}
+		})
+	}
+}
+
+func TestDenseGeneratedConfig(t *testing.T) {
+	p, result := NewParser("dense", cfggen.Generate(denseScale), WithLimits(generatedLimits))
+	if len(result) != 0 {
+		t.Fatalf("unexpected validation results: %v", result)
+	}
+	ceCtx, result := p.Parse()
+	if ceCtx != nil {
+		t.Error("expected no config execution context")
+	}
+	if len(result) == 0 || !HasErrors(result) {
+		t.Fatal("expected the dense config to exceed the max paths")
+	}
+	for _, r := range result {
+		if r.Code != CodeLimitExceeded {
+			t.Errorf("unexpected parse result: %v", r)
+		}
+	}
+}
+
+func BenchmarkNewParser(b *testing.B) {
+	for _, cfg := range scales {
+		ctrlCfg := cfggen.Generate(cfg)
+		b.Run(scaleName(cfg), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, result := NewParser("bench", ctrlCfg); len(result) != 0 {
+					b.Fatal(result)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkValidateSyntax(b *testing.B) {
+	for _, cfg := range scales {
+		p := newBenchmarkParser(b, cfg)
+		b.Run(scaleName(cfg), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if result := p.ValidateSyntax(); len(result) != 0 {
+					b.Fatal(result)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, cfg := range append(scales, denseScale) {
+		ctrlCfg := cfggen.Generate(cfg)
+		b.Run(scaleName(cfg), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				// every parse runs on a fresh parser, such that nothing is
+				// reused from an earlier parse
+				b.StopTimer()
+				p, result := NewParser("bench", ctrlCfg)
+				if len(result) != 0 {
+					b.Fatal(result)
+				}
+				b.StartTimer()
+				if _, result := p.Parse(); len(result) != 0 {
+					b.Fatal(result)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTransitiveReduction(b *testing.B) {
+	for _, cfg := range append(scales, denseScale) {
+		p := newBenchmarkParser(b, cfg)
+		b.Run(scaleName(cfg), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				// the reduction runs on a connected, not yet reduced graph
+				b.StopTimer()
+				ceCtx := connectedConfig(b, p)
+				b.StartTimer()
+				p.transitivereduction(ceCtx)
+			}
+		})
+	}
+}
+
+func BenchmarkGetExternalResources(b *testing.B) {
+	for _, cfg := range scales {
+		p := newBenchmarkParser(b, cfg)
+		b.Run(scaleName(cfg), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, result := p.GetExternalResources(); len(result) != 0 {
+					b.Fatal(result)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetImages(b *testing.B) {
+	for _, cfg := range scales {
+		p := newBenchmarkParser(b, cfg)
+		b.Run(scaleName(cfg), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if images := p.GetImages(); len(images) == 0 {
+					b.Fatal("expected images")
+				}
+			}
+		})
+	}
+}
+
+// connectedConfig runs the parse phases up to the transitive reduction
+func connectedConfig(b *testing.B, p *parser) ConfigExecutionContext {
+	ctx := context.Background()
+	ceCtx, gvar, result := p.init(ctx)
+	if len(result) != 0 {
+		b.Fatal(result)
+	}
+	if result := p.populate(ctx, ceCtx, gvar, nil); len(result) != 0 {
+		b.Fatal(result)
+	}
+	if result := p.resolve(ctx, ceCtx, gvar, nil); len(result) != 0 {
+		b.Fatal(result)
+	}
+	if result := p.connect(ctx, ceCtx, gvar, nil); len(result) != 0 {
+		b.Fatal(result)
+	}
+	return ceCtx
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigSchemaRegistry holds the json schemas the configs of the functions
+// are validated against. A schema is registered per image, an image without
+// tag and digest matches all the tags and digests of the image.
+type ConfigSchemaRegistry interface {
+	Register(image string, schema []byte) error
+	GetSchema(image string) *spec.Schema
+}
+
+func NewConfigSchemaRegistry() ConfigSchemaRegistry {
+	return &configSchemaRegistry{
+		schemas: map[string]*spec.Schema{},
+	}
+}
+
+type configSchemaRegistry struct {
+	m       sync.RWMutex
+	schemas map[string]*spec.Schema
+}
+
+// configSchemaKeys returns the key of the image and the key of the image
+// repository
+func configSchemaKeys(image string) (string, string, error) {
+	info, err := parseImageReference(image)
+	if err != nil {
+		return "", "", err
+	}
+	repo := info.Registry + "/" + info.Repository
+	key := repo
+	if info.Tag != "" {
+		key = key + ":" + info.Tag
+	}
+	if info.Digest != "" {
+		key = key + "@" + info.Digest
+	}
+	return key, repo, nil
+}
+
+func (r *configSchemaRegistry) Register(image string, b []byte) error {
+	key, _, err := configSchemaKeys(image)
+	if err != nil {
+		return err
+	}
+	// the schema can be supplied in json or yaml
+	jb, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return fmt.Errorf("invalid schema for image %s: %s", image, err.Error())
+	}
+	s := &spec.Schema{}
+	if err := json.Unmarshal(jb, s); err != nil {
+		return fmt.Errorf("invalid schema for image %s: %s", image, err.Error())
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.schemas[key] = s
+	return nil
+}
+
+// GetSchema returns the schema of the image, a schema registered for the
+// exact image takes precedence over a schema registered for the repository
+func (r *configSchemaRegistry) GetSchema(image string) *spec.Schema {
+	key, repo, err := configSchemaKeys(image)
+	if err != nil {
+		return nil
+	}
+	r.m.RLock()
+	defer r.m.RUnlock()
+	if s, ok := r.schemas[key]; ok {
+		return s
+	}
+	return r.schemas[repo]
+}
+
+// ValidateConfigs validates the config of the functions and services against
+// the schema registered for their image. Functions without image or without
+// registered schema are not validated.
+func (r *parser) ValidateConfigs(registry ConfigSchemaRegistry) []Result {
+	cv := &cv{
+		ir:       r.ir,
+		result:   []Result{},
+		registry: registry,
+	}
+
+	fnc := &WalkConfig{
+		gvkObjectFn: cv.getGvk,
+		functionFn:  cv.validateConfig,
+		serviceFn:   cv.validateConfig,
+	}
+
+	// walk the config to validate the function configs
+	r.walkControllerConfig(fnc)
+	return DeduplicateResults(cv.result)
+}
+
+type cv struct {
+	ir       *configIR
+	mr       sync.RWMutex
+	result   []Result
+	registry ConfigSchemaRegistry
+}
+
+func (r *cv) recordResult(result Result) {
+	r.mr.Lock()
+	defer r.mr.Unlock()
+	r.result = append(r.result, result)
+}
+
+func (r *cv) getGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *schema.GroupVersionKind {
+	gvk, _ := r.ir.GetGVK(v.Resource)
+	return gvk
+}
+
+func (r *cv) validateConfig(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
+	if v.Image == "" || r.registry == nil {
+		return
+	}
+	s := r.registry.GetSchema(v.Image)
+	if s == nil {
+		return
+	}
+
+	var data any
+	if str, ok := v.GetConfigString(); ok {
+		// a string config is validated if it contains a yaml or json document
+		jb, err := yaml.YAMLToJSON([]byte(str))
+		if err == nil {
+			err = json.Unmarshal(jb, &data)
+		}
+		if err != nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("config of image %s cannot be validated: %s", v.Image, err.Error()).Error(),
+			})
+			return
+		}
+	} else if v.HasConfig() {
+		if err := json.Unmarshal(v.Config.Raw, &data); err != nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("config of image %s cannot be validated: %s", v.Image, err.Error()).Error(),
+			})
+			return
+		}
+	}
+
+	if err := validate.AgainstSchema(s, data, strfmt.Default); err != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("config of image %s does not match its schema: %s", v.Image, err.Error()).Error(),
+		})
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// configSchemaConfig runs a container function, the config of the function
+// is supplied by the test
+const configSchemaConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+pipelines:
+- name: apply
+  tasks:
+    fn:
+      type: container
+      image: fn:v1
+      config: %s
+      output:
+        nodes:
+          internal: true
+          resource:
+            apiVersion: topo.yndd.io/v1alpha1
+            kind: Node
+- name: delete
+`
+
+const (
+	// nameSchema requires a config with a string name
+	nameSchema = `
+type: object
+required: [name]
+properties:
+  name:
+    type: string
+`
+	anySchema = `type: object`
+)
+
+func TestValidateConfigs(t *testing.T) {
+	cases := map[string]struct {
+		config  string
+		schemas map[string]string
+		want    string
+	}{
+		"ValidObject": {
+			config:  "{apiVersion: example.com/v1, kind: Config, name: a}",
+			schemas: map[string]string{"fn": nameSchema},
+		},
+		"ValidString": {
+			config:  `"name: a"`,
+			schemas: map[string]string{"fn": nameSchema},
+		},
+		"InvalidObject": {
+			config:  "{apiVersion: example.com/v1, kind: Config, name: 1}",
+			schemas: map[string]string{"fn": nameSchema},
+			want:    "config of image fn:v1 does not match its schema",
+		},
+		"MissingField": {
+			config:  "{apiVersion: example.com/v1, kind: Config, other: a}",
+			schemas: map[string]string{"fn": nameSchema},
+			want:    "config of image fn:v1 does not match its schema",
+		},
+		"UnparsableString": {
+			config:  `"name: ["`,
+			schemas: map[string]string{"fn": nameSchema},
+			want:    "config of image fn:v1 cannot be validated",
+		},
+		"MissingSchema": {
+			config:  "{apiVersion: example.com/v1, kind: Config, name: 1}",
+			schemas: map[string]string{"other": nameSchema},
+		},
+		"NoRegistry": {
+			config: "{apiVersion: example.com/v1, kind: Config, name: 1}",
+		},
+		"TagTakesPrecedence": {
+			config:  "{apiVersion: example.com/v1, kind: Config, name: 1}",
+			schemas: map[string]string{"fn": nameSchema, "fn:v1": anySchema},
+		},
+		"OtherTag": {
+			config:  "{apiVersion: example.com/v1, kind: Config, name: 1}",
+			schemas: map[string]string{"fn": nameSchema, "fn:v2": anySchema},
+			want:    "config of image fn:v1 does not match its schema",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+			if err := yaml.Unmarshal([]byte(fmt.Sprintf(configSchemaConfig, c.config)), cfg); err != nil {
+				t.Fatal(err)
+			}
+			p, result := NewParser("configschema", cfg)
+			if len(result) != 0 {
+				t.Fatalf("unexpected validation results: %v", result)
+			}
+			var registry ConfigSchemaRegistry
+			if c.schemas != nil {
+				registry = NewConfigSchemaRegistry()
+				for image, schema := range c.schemas {
+					if err := registry.Register(image, []byte(schema)); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			result = p.ValidateConfigs(registry)
+			if c.want == "" {
+				if len(result) != 0 {
+					t.Errorf("unexpected results: %v", result)
+				}
+				return
+			}
+			if len(result) != 1 || !strings.HasPrefix(result[0].Error, c.want) {
+				t.Errorf("got %v, want 1 result %s", result, c.want)
+			}
+		})
+	}
+}
+
+func TestConfigString(t *testing.T) {
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal([]byte(fmt.Sprintf(configSchemaConfig, `"name: a"`)), cfg); err != nil {
+		t.Fatal(err)
+	}
+	fn := cfg.Pipelines[0].Tasks["fn"].Function
+	s, ok := fn.GetConfigString()
+	if !ok || s != "name: a" {
+		t.Fatalf("got config %q %t, want %q", s, ok, "name: a")
+	}
+	// a string set in go has the same encoding as a string in yaml
+	set := &ctrlcfgv1alpha1.Function{}
+	set.SetConfigString(s)
+	if string(set.Config.Raw) != string(fn.Config.Raw) {
+		t.Errorf("got raw config %s, want %s", set.Config.Raw, fn.Config.Raw)
+	}
+}
@@ -17,23 +17,26 @@ limitations under the License.
 package ccsyntax
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
 	"github.com/fnrunner/fnruntime/pkg/exec/rtdag"
 	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
-	"github.com/fnrunner/fnutils/pkg/meta"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-func (r *parser) connect(ceCtx ConfigExecutionContext, gvar GlobalVariable) []Result {
+func (r *parser) connect(ctx context.Context, ceCtx ConfigExecutionContext, gvar GlobalVariable, failed *failedVertices) []Result {
 	c := &connector{
+		ir:     r.ir,
 		ceCtx:  ceCtx,
 		gvar:   gvar,
+		failed: failed,
 		result: []Result{},
 	}
 
 	fnc := &WalkConfig{
+		ctx:         ctx,
 		gvkObjectFn: c.connectGvk,
 		functionFn:  c.connectFunction,
 	}
@@ -45,8 +48,10 @@ func (r *parser) connect(ceCtx ConfigExecutionContext, gvar GlobalVariable) []Re
 }
 
 type connector struct {
+	ir     *configIR
 	ceCtx  ConfigExecutionContext
 	gvar   GlobalVariable
+	failed *failedVertices
 	mr     sync.RWMutex
 	result []Result
 }
@@ -58,21 +63,31 @@ func (r *connector) recordResult(result Result) {
 }
 
 func (r *connector) connectGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *schema.GroupVersionKind {
-	gvk, err := meta.GetGVKFromRuntimeRawExtension(v.Resource)
+	gvk, err := r.ir.GetGVK(v.Resource)
+	if r.failed.has(oc) {
+		return gvk
+	}
 	if err != nil {
 		r.recordResult(Result{
 			OriginContext: oc,
 			Error:         err.Error(),
+			Code:          CodeInvalidGVK,
 		})
 	}
 	return gvk
 }
 
 func (r *connector) connectFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
+	if r.failed.has(oc) {
+		return
+	}
+	if r.ceCtx.GetDAG(oc) == nil {
+		return
+	}
 
-	for localVarName, v := range v.Vars {
+	for _, localVarName := range sortedKeys(v.Vars) {
 		oc.LocalVarName = localVarName
-		r.connectRefs(oc, v)
+		r.connectRefs(oc, v.Vars[localVarName])
 	}
 
 	if v.HasBlock() {
@@ -90,24 +105,9 @@ func (r *connector) connectFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Functi
 				d.Connect(oc.RootVertexName, oc.VertexName)
 			}
 		}
-		if v.Input.Key != "" {
-			r.connectRefs(oc, v.Input.Key)
-		}
-		if v.Input.Value != "" {
-			r.connectRefs(oc, v.Input.Value)
-		}
-		if v.Input.Expression != "" {
-			r.connectRefs(oc, v.Input.Expression)
-		}
-		for _, v := range v.Input.GenericInput {
-			r.connectRefs(oc, v)
-		}
-		if v.Input.Selector != nil {
-			for k, v := range v.Input.Selector.MatchLabels {
-				r.connectRefs(oc, k)
-				r.connectRefs(oc, v)
-			}
-		}
+	}
+	for _, ref := range getFunctionTypeHandler(v.Type).References(v) {
+		r.connectRefs(oc, ref)
 	}
 
 	// A block needs an explicit dependency to the root Block Vertex
@@ -121,6 +121,32 @@ func (r *connector) connectFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Functi
 			r.connectVertex(oc, vertexName)
 		}
 	}
+
+	// A serviceRef is annotated as a service edge in the dag context
+	if v.ServiceRef != nil {
+		r.connectService(oc, v.ServiceRef)
+	}
+}
+
+func (r *connector) connectService(oc *OriginContext, v *ctrlcfgv1alpha1.ServiceRef) {
+	svcCtx := r.ceCtx.GetService(v.Name)
+	if svcCtx == nil {
+		// the resolver reports the service that does not exist
+		return
+	}
+	e := &ServiceEdge{
+		Service:    v.Name,
+		Output:     v.Output,
+		VertexName: oc.VertexName,
+	}
+	if svcOutput, ok := svcCtx.Fn.Output[v.Output]; ok && svcOutput != nil {
+		if gvk, err := r.ir.GetGVK(svcOutput.Resource); err == nil {
+			e.GVK = *gvk
+		}
+	}
+	if dctx := r.ceCtx.GetDAGCtx(oc.FOWS, oc.GVK, oc.Operation); dctx != nil {
+		dctx.AddServiceEdge(e)
+	}
 }
 
 func (r *connector) connectBlock(oc *OriginContext, v ctrlcfgv1alpha1.Block) {
@@ -141,20 +167,23 @@ func (r *connector) connectBlock(oc *OriginContext, v ctrlcfgv1alpha1.Block) {
 }
 
 func (r *connector) connectRefs(oc *OriginContext, s string) {
-	rfs := NewReferences()
-	refs := rfs.GetReferences(s)
+	refs := r.ir.GetReferences(s)
 
 	for _, ref := range refs {
 		// RangeRefKind do nothing
 		// for regular values we resolve the variables
 		// for variables that start with _ this is a special case and
 		// should only be used within a jq construct
-		if ref.Kind == RegularReferenceKind && ref.Value[0] != '_' {
+		if ref.Kind == RegularReferenceKind && ref.Value != "" && ref.Value[0] != '_' {
 			// get the vertexContext from the function
 			//fmt.Printf("oc: %#v, ref: %#v, gvk: %s\n", oc, ref, oc.GVK.String())
 			d := r.ceCtx.GetDAG(oc)
 			if d == nil {
-				fmt.Printf("empty dag\n")
+				r.recordResult(Result{
+					OriginContext: oc,
+					Error:         fmt.Errorf("dag not found for %s", oc.VertexName).Error(),
+				})
+				return
 			}
 			//fmt.Printf("vertexName: %s\n", oc.VertexName)
 			//d.PrintVertices()
@@ -164,6 +193,7 @@ func (r *connector) connectRefs(oc *OriginContext, s string) {
 					OriginContext: oc,
 					Error:         fmt.Errorf("wrong type expect vertexContext: %#v", vc).Error(),
 				})
+				return
 			}
 			//fmt.Printf("vc: %#v\n", vc)
 			// lookup the localDAG first
@@ -184,9 +214,14 @@ func (r *connector) connectRefs(oc *OriginContext, s string) {
 				r.recordResult(Result{
 					OriginContext: oc,
 					Error:         fmt.Errorf("variable not found in gvar dag, varName: %s", ref.Value).Error(),
+					Code:          CodeUnresolvedReference,
 				})
 				continue
 			}
+			// do not connect to a vertex that failed in a previous phase
+			if r.failed.hasVertex(oc, varInfo.OutputVertex) {
+				continue
+			}
 
 			switch {
 			case varInfo.BlockIndex < oc.BlockIndex:
@@ -212,6 +247,9 @@ func (r *connector) connectRefs(oc *OriginContext, s string) {
 
 func (r *connector) connectVertex(oc *OriginContext, vertexName string) {
 	d := r.ceCtx.GetDAG(oc)
+	if d == nil || r.failed.hasVertex(oc, vertexName) {
+		return
+	}
 	d.Connect(vertexName, oc.VertexName)
 
 }
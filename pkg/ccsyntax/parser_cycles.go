@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fnrunner/fnruntime/pkg/exec/rtdag"
+)
+
+// checkCycles reports the cycles in the dags of the config execution
+// context, the transitive reduction does not terminate on a cycle
+func (r *parser) checkCycles(ceCtx ConfigExecutionContext) []Result {
+	result := []Result{}
+	for _, fow := range []FOWS{FOWFor, FOWWatch} {
+		fowDAGs := ceCtx.GetFOW(fow)
+		for _, gvk := range sortedGVKs(fowDAGs) {
+			gvk := gvk
+			for _, op := range sortedKeys(fowDAGs[gvk]) {
+				dctx := fowDAGs[gvk][op]
+				oc := &OriginContext{FOWS: fow, RootVertexName: dctx.RootVertexName, GVK: &gvk, Operation: op}
+				result = append(result, cycleResults(oc, dctx.DAG)...)
+				for _, blockVertexName := range sortedKeys(dctx.BlockDAGs) {
+					oc := oc.DeepCopy()
+					oc.Block = true
+					oc.BlockVertexName = blockVertexName
+					result = append(result, cycleResults(oc, dctx.BlockDAGs[blockVertexName])...)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func cycleResults(oc *OriginContext, d rtdag.RuntimeDAG) []Result {
+	result := []Result{}
+	for _, cycle := range findCycles(d) {
+		oc := oc.DeepCopy()
+		oc.VertexName = cycle[0]
+		result = append(result, Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> ")).Error(),
+			Code:          CodeDependencyCycle,
+		})
+	}
+	return result
+}
+
+// findCycles returns a cycle for every edge that closes one in a depth first
+// walk of the dag, a cycle starts and ends with the same vertex
+func findCycles(d rtdag.RuntimeDAG) [][]string {
+	const (
+		onWalk = iota + 1
+		done
+	)
+	state := map[string]int{}
+	walk := []string{}
+	cycles := [][]string{}
+	var visit func(vertexName string)
+	visit = func(vertexName string) {
+		state[vertexName] = onWalk
+		walk = append(walk, vertexName)
+		downVertexNames := d.GetDownVertexes(vertexName)
+		sort.Strings(downVertexNames)
+		for _, downVertexName := range downVertexNames {
+			switch state[downVertexName] {
+			case onWalk:
+				for i := len(walk) - 1; i >= 0; i-- {
+					if walk[i] == downVertexName {
+						cycle := append([]string{}, walk[i:]...)
+						cycles = append(cycles, append(cycle, downVertexName))
+						break
+					}
+				}
+			case 0:
+				visit(downVertexName)
+			}
+		}
+		walk = walk[:len(walk)-1]
+		state[vertexName] = done
+	}
+	for _, vertexName := range sortedKeys(d.GetVertices()) {
+		if state[vertexName] == 0 {
+			visit(vertexName)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+const cycleConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+pipelines:
+- name: delete
+- name: apply
+  tasks:
+%s
+`
+
+func TestDependencyCycle(t *testing.T) {
+	cases := map[string]struct {
+		tasks      string
+		vertexName string
+		want       string
+	}{
+		"SelfReference": {
+			tasks: `
+    self:
+      type: jq
+      input:
+        expression: $self`,
+			vertexName: "self",
+			want:       "dependency cycle: self -> self",
+		},
+		"Cycle": {
+			tasks: `
+    a:
+      type: jq
+      input:
+        expression: $b
+    b:
+      type: jq
+      input:
+        expression: $a`,
+			vertexName: "a",
+			want:       "dependency cycle: a -> b -> a",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+			if err := yaml.Unmarshal([]byte(fmt.Sprintf(cycleConfig, c.tasks)), cfg); err != nil {
+				t.Fatal(err)
+			}
+			p, result := NewParser("test", cfg)
+			if len(result) != 0 {
+				t.Fatalf("unexpected validation results: %v", result)
+			}
+			// the dag with a cycle is not reduced in either parse mode
+			for mode, parse := range map[string]func() (ConfigExecutionContext, []Result){"Parse": p.Parse, "ParseAll": p.ParseAll} {
+				ceCtx, result := parse()
+				if ceCtx != nil {
+					t.Errorf("%s: expected no config execution context", mode)
+				}
+				if len(result) != 1 || result[0].Code != CodeDependencyCycle || !hasResult(result, c.vertexName, c.want) {
+					t.Errorf("%s: got %v, want %s for %s", mode, result, c.want, c.vertexName)
+				}
+			}
+		})
+	}
+}
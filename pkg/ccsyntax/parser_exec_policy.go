@@ -0,0 +1,191 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const wasmExtension = ".wasm"
+
+// ExecPolicy defines the rules the exec commands in the controller config
+// have to comply with
+type ExecPolicy struct {
+	// AllowedBinaries lists the binaries exec commands can run. An entry is
+	// either an absolute path or a binary name that matches the binary of
+	// the command regardless of its directory.
+	AllowedBinaries []string `json:"allowedBinaries,omitempty" yaml:"allowedBinaries,omitempty"`
+	// LocalDev resolves binaries that are not in the allowlist on the local
+	// filesystem, relative paths are resolved against the working directory
+	// and bare names against the PATH. The lint stays offline.
+	LocalDev bool `json:"localDev,omitempty" yaml:"localDev,omitempty"`
+	// Severity of the policy violations, defaults to error
+	Severity Severity `json:"severity,omitempty" yaml:"severity,omitempty"`
+	// Stat and LookPath resolve the binaries for LocalDev, they default to
+	// os.Stat and exec.LookPath
+	Stat     func(name string) (fs.FileInfo, error) `json:"-" yaml:"-"`
+	LookPath func(file string) (string, error)      `json:"-" yaml:"-"`
+}
+
+// LintExec lints the exec commands of the functions against the exec policy.
+// Commands that cannot be split shell-style are always reported as an error,
+// policy violations with the severity of the policy.
+func (r *parser) LintExec(policy *ExecPolicy) []Result {
+	inv, _ := r.GetImageInventory()
+	result := []Result{}
+	if policy == nil {
+		policy = &ExecPolicy{}
+	}
+	severity := policy.Severity
+	if severity == "" {
+		severity = SeverityError
+	}
+	for _, image := range inv.Images {
+		if image.Kind != ExecutorKindExec {
+			continue
+		}
+		args, err := splitCommand(image.Name)
+		if err != nil {
+			for _, oc := range image.Usages {
+				result = append(result, Result{
+					OriginContext: oc,
+					Error:         fmt.Errorf("invalid exec %s: %s", image.Name, err.Error()).Error(),
+					Severity:      SeverityError,
+				})
+			}
+			continue
+		}
+		if err := policy.validate(args[0]); err != nil {
+			for _, oc := range image.Usages {
+				result = append(result, Result{
+					OriginContext: oc,
+					Error:         err.Error(),
+					Severity:      severity,
+				})
+			}
+		}
+	}
+	return DeduplicateResults(result)
+}
+
+func (r *ExecPolicy) validate(binary string) error {
+	for _, allowed := range r.AllowedBinaries {
+		if binary == allowed {
+			return nil
+		}
+		// a bare name in the allowlist matches the binary in any directory
+		if !strings.Contains(allowed, "/") && filepath.Base(binary) == allowed {
+			return nil
+		}
+	}
+	if !r.LocalDev {
+		return fmt.Errorf("exec binary %s is not in the allowlist", binary)
+	}
+	if strings.Contains(binary, "/") {
+		stat := r.Stat
+		if stat == nil {
+			stat = os.Stat
+		}
+		fi, err := stat(binary)
+		if err != nil {
+			return fmt.Errorf("exec binary %s not found: %s", binary, err.Error())
+		}
+		if fi.IsDir() || fi.Mode().Perm()&0111 == 0 {
+			return fmt.Errorf("exec binary %s is not executable", binary)
+		}
+		return nil
+	}
+	lookPath := r.LookPath
+	if lookPath == nil {
+		lookPath = exec.LookPath
+	}
+	if _, err := lookPath(binary); err != nil {
+		return fmt.Errorf("exec binary %s not found in PATH", binary)
+	}
+	return nil
+}
+
+// isWasmPath returns true if the wasm module is referenced by a file path
+// instead of an OCI reference
+func isWasmPath(s string) bool {
+	return strings.HasSuffix(s, wasmExtension)
+}
+
+// splitCommand splits a command line shell-style in its arguments. Single
+// quotes preserve their content literally, double quotes and unquoted text
+// support backslash escapes. The command is not run by a shell, so no
+// expansion is performed.
+func splitCommand(s string) ([]string, error) {
+	args := []string{}
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			arg.WriteRune(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(c)
+			}
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("command ends with an escape character")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return args, nil
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// execConfig runs a container function, the exec of the function is set by
+// the test
+const execConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+pipelines:
+- name: apply
+  tasks:
+    fn:
+      type: container
+      exec: tool
+      output:
+        nodes:
+          internal: true
+          resource:
+            apiVersion: topo.yndd.io/v1alpha1
+            kind: Node
+- name: delete
+`
+
+func TestSplitCommand(t *testing.T) {
+	cases := map[string]struct {
+		command string
+		want    []string
+		err     string
+	}{
+		"Single":             {command: "tool", want: []string{"tool"}},
+		"Whitespace":         {command: " tool\t-a \n b ", want: []string{"tool", "-a", "b"}},
+		"SingleQuotes":       {command: `tool 'a b' '\n'`, want: []string{"tool", "a b", `\n`}},
+		"DoubleQuotes":       {command: `tool "a 'b'" "c\"d"`, want: []string{"tool", "a 'b'", `c"d`}},
+		"AdjacentQuotes":     {command: `tool a"b c"'d'`, want: []string{"tool", "ab cd"}},
+		"EmptyQuotes":        {command: `tool "" ''`, want: []string{"tool", "", ""}},
+		"EscapedSpace":       {command: `tool a\ b`, want: []string{"tool", "a b"}},
+		"EscapedBackslash":   {command: `tool a\\b`, want: []string{"tool", `a\b`}},
+		"NoExpansion":        {command: "tool $HOME *", want: []string{"tool", "$HOME", "*"}},
+		"Empty":              {command: "", err: "empty command"},
+		"Blank":              {command: " \t", err: "empty command"},
+		"TrailingEscape":     {command: `tool a\`, err: "command ends with an escape character"},
+		"UnterminatedQuote":  {command: `tool "a`, err: `unterminated " quote`},
+		"UnterminatedSingle": {command: `tool 'a`, err: "unterminated ' quote"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := splitCommand(c.command)
+			if c.err != "" {
+				if err == nil || err.Error() != c.err {
+					t.Fatalf("got error %v, want %s", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestIsWasmPath(t *testing.T) {
+	cases := map[string]bool{
+		"./fn.wasm":                           true,
+		"/opt/fn/fn.wasm":                     true,
+		"fn.wasm":                             true,
+		"europe-docker.pkg.dev/srlinux/fn:v1": false,
+		"fn":                                  false,
+		"fn.wasm:v1":                          false,
+	}
+	for s, want := range cases {
+		t.Run(s, func(t *testing.T) {
+			if got := isWasmPath(s); got != want {
+				t.Errorf("got %t, want %t", got, want)
+			}
+		})
+	}
+}
+
+func TestLintExec(t *testing.T) {
+	// the local filesystem and PATH of the LocalDev cases
+	fsys := fstest.MapFS{
+		"usr/bin/tool": {Mode: 0755},
+		"usr/bin/data": {Mode: 0644},
+		"usr/bin/dir":  {Mode: fs.ModeDir | 0755},
+	}
+	stat := func(name string) (fs.FileInfo, error) {
+		return fs.Stat(fsys, strings.TrimPrefix(name, "/"))
+	}
+	lookPath := func(file string) (string, error) {
+		if file == "tool" {
+			return "/usr/bin/tool", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	cases := map[string]struct {
+		exec     string
+		policy   *ExecPolicy
+		want     string
+		severity Severity
+	}{
+		"NoPolicy": {
+			exec:     "tool",
+			want:     "exec binary tool is not in the allowlist",
+			severity: SeverityError,
+		},
+		"AllowedPath": {
+			exec:   "/usr/bin/tool --flag",
+			policy: &ExecPolicy{AllowedBinaries: []string{"/usr/bin/tool"}},
+		},
+		"AllowedName": {
+			exec:   "/opt/bin/tool",
+			policy: &ExecPolicy{AllowedBinaries: []string{"tool"}},
+		},
+		"AllowedPathOtherDirectory": {
+			exec:     "/opt/bin/tool",
+			policy:   &ExecPolicy{AllowedBinaries: []string{"/usr/bin/tool"}},
+			want:     "exec binary /opt/bin/tool is not in the allowlist",
+			severity: SeverityError,
+		},
+		"Warning": {
+			exec:     "other",
+			policy:   &ExecPolicy{AllowedBinaries: []string{"tool"}, Severity: SeverityWarning},
+			want:     "exec binary other is not in the allowlist",
+			severity: SeverityWarning,
+		},
+		"InvalidCommand": {
+			// a command that cannot be split is an error whatever the severity
+			exec:     `tool "a`,
+			policy:   &ExecPolicy{Severity: SeverityWarning},
+			want:     `invalid exec tool "a: unterminated " quote`,
+			severity: SeverityError,
+		},
+		"LocalDevPath": {
+			exec:   "/usr/bin/tool",
+			policy: &ExecPolicy{LocalDev: true, Stat: stat, LookPath: lookPath},
+		},
+		"LocalDevMissingPath": {
+			exec:     "/usr/bin/missing",
+			policy:   &ExecPolicy{LocalDev: true, Stat: stat, LookPath: lookPath},
+			want:     "exec binary /usr/bin/missing not found",
+			severity: SeverityError,
+		},
+		"LocalDevNotExecutable": {
+			exec:     "/usr/bin/data",
+			policy:   &ExecPolicy{LocalDev: true, Stat: stat, LookPath: lookPath},
+			want:     "exec binary /usr/bin/data is not executable",
+			severity: SeverityError,
+		},
+		"LocalDevDirectory": {
+			exec:     "/usr/bin/dir",
+			policy:   &ExecPolicy{LocalDev: true, Stat: stat, LookPath: lookPath},
+			want:     "exec binary /usr/bin/dir is not executable",
+			severity: SeverityError,
+		},
+		"LocalDevPATH": {
+			exec:   "tool -a",
+			policy: &ExecPolicy{LocalDev: true, Stat: stat, LookPath: lookPath},
+		},
+		"LocalDevMissingPATH": {
+			exec:     "other",
+			policy:   &ExecPolicy{LocalDev: true, Stat: stat, LookPath: lookPath},
+			want:     "exec binary other not found in PATH",
+			severity: SeverityError,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+			if err := yaml.Unmarshal([]byte(execConfig), cfg); err != nil {
+				t.Fatal(err)
+			}
+			cfg.Pipelines[0].Tasks["fn"].Exec = c.exec
+			// the invalid command is reported by the validation as well
+			p, _ := NewParser("exec", cfg)
+			result := p.LintExec(c.policy)
+			if c.want == "" {
+				if len(result) != 0 {
+					t.Errorf("unexpected results: %v", result)
+				}
+				return
+			}
+			if len(result) != 1 || !strings.HasPrefix(result[0].Error, c.want) {
+				t.Fatalf("got %v, want 1 result %s", result, c.want)
+			}
+			if result[0].Severity != c.severity {
+				t.Errorf("got severity %s, want %s", result[0].Severity, c.severity)
+			}
+		})
+	}
+}
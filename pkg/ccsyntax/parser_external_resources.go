@@ -20,18 +20,34 @@ import (
 	"sync"
 
 	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
-	"github.com/fnrunner/fnutils/pkg/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// GetExternalResources returns the deduplicated list of gvks the controller
+// interacts with through the api server
 func (r *parser) GetExternalResources() ([]*schema.GroupVersionKind, []Result) {
+	ers, result := r.GetExternalResourceUsages()
+	gvks := make([]*schema.GroupVersionKind, 0, len(ers))
+	for _, er := range ers {
+		if er.IsExternal() {
+			gvk := er.GVK
+			gvks = append(gvks, &gvk)
+		}
+	}
+	return gvks, result
+}
+
+// GetExternalResourceUsages returns every gvk used in the controller config
+// together with the places it is used and how it is accessed
+func (r *parser) GetExternalResourceUsages() ([]*ExternalResource, []Result) {
 	er := &er{
+		ir:        r.ir,
 		result:    []Result{},
-		resources: []*schema.GroupVersionKind{},
+		resources: []*ExternalResource{},
 	}
 	er.resultFn = er.recordResult
-	er.addKindFn = er.addGVK
+	er.addKindFn = er.addUsage
 
 	fnc := &WalkConfig{
 		gvkObjectFn: er.getGvk,
@@ -41,19 +57,64 @@ func (r *parser) GetExternalResources() ([]*schema.GroupVersionKind, []Result) {
 
 	// validate the external resources
 	r.walkControllerConfig(fnc)
-	return er.resources, er.result
+	return er.resources, DeduplicateResults(er.result)
+}
+
+type ResourceAccess string
+
+const (
+	ResourceAccessRead  ResourceAccess = "read"
+	ResourceAccessWrite ResourceAccess = "write"
+	ResourceAccessWatch ResourceAccess = "watch"
+)
+
+// ExternalResource contains a gvk and all the places it is used in
+type ExternalResource struct {
+	GVK    schema.GroupVersionKind `json:"gvk" yaml:"gvk"`
+	Usages []*ResourceUsage        `json:"usages,omitempty" yaml:"usages,omitempty"`
+}
+
+// ResourceUsage contains the origin in which the resource is used, how it is
+// accessed and if the resource is internal to the controller or created
+// in the api server
+type ResourceUsage struct {
+	OriginContext *OriginContext `json:"originContext,omitempty" yaml:"originContext,omitempty"`
+	Access        ResourceAccess `json:"access" yaml:"access"`
+	Internal      bool           `json:"internal" yaml:"internal"`
+}
+
+// IsExternal returns true if at least 1 usage interacts with the api server
+func (r *ExternalResource) IsExternal() bool {
+	for _, u := range r.Usages {
+		if !u.Internal {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAccess returns true if the resource is accessed in the api server with
+// the supplied access
+func (r *ExternalResource) HasAccess(access ResourceAccess) bool {
+	for _, u := range r.Usages {
+		if !u.Internal && u.Access == access {
+			return true
+		}
+	}
+	return false
 }
 
 type er struct {
+	ir        *configIR
 	mr        sync.RWMutex
 	result    []Result
 	resultFn  recordResultFn
 	mrs       sync.RWMutex
-	resources []*schema.GroupVersionKind
+	resources []*ExternalResource
 	addKindFn erAddKindFn
 }
 
-type erAddKindFn func(*schema.GroupVersionKind)
+type erAddKindFn func(*schema.GroupVersionKind, *ResourceUsage)
 
 func (r *er) recordResult(result Result) {
 	r.mr.Lock()
@@ -61,58 +122,60 @@ func (r *er) recordResult(result Result) {
 	r.result = append(r.result, result)
 }
 
-func (r *er) addGVK(gvk *schema.GroupVersionKind) {
-	//fmt.Printf("add gvk: %v \n", gvk)
+func (r *er) addUsage(gvk *schema.GroupVersionKind, u *ResourceUsage) {
+	if gvk == nil {
+		return
+	}
 	r.mrs.Lock()
 	defer r.mrs.Unlock()
-	found := false
 	for _, resource := range r.resources {
-		if resource.Group == gvk.Group &&
-			resource.Version == gvk.Version &&
-			resource.Kind == gvk.Kind {
+		if resource.GVK.Group == gvk.Group &&
+			resource.GVK.Version == gvk.Version &&
+			resource.GVK.Kind == gvk.Kind {
+			resource.Usages = append(resource.Usages, u)
 			return
 		}
 	}
-	if !found {
-		r.resources = append(r.resources, gvk)
-	}
+	r.resources = append(r.resources, &ExternalResource{
+		GVK:    *gvk,
+		Usages: []*ResourceUsage{u},
+	})
 }
 
-func (r *er) addGvk(gvk *schema.GroupVersionKind) {
-	r.addGVK(gvk)
+func (r *er) addGvk(oc *OriginContext, gvk *schema.GroupVersionKind, access ResourceAccess, internal bool) {
+	r.addKindFn(gvk, &ResourceUsage{
+		OriginContext: oc.DeepCopy(),
+		Access:        access,
+		Internal:      internal,
+	})
 }
 
 func (r *er) getGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *schema.GroupVersionKind {
 	gvk := r.getgvk(oc, v.Resource)
-	r.addGvk(gvk)
+	r.addGvk(oc, gvk, ResourceAccessWatch, false)
 	return gvk
 }
 
 func (r *er) getFunctionGvk(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
-	if v.Input != nil && len(v.Input.Resource.Raw) != 0 {
-		gvk := r.getgvk(oc, v.Input.Resource)
-		r.addGvk(gvk)
-	}
-	if v.Type == ctrlcfgv1alpha1.GoTemplateType {
-		if len(v.Input.Resource.Raw) != 0 {
-			gvk := r.getgvk(oc, v.Input.Resource)
-			r.addGvk(gvk)
-		}
+	frs, errs := getFunctionTypeHandler(v.Type).ExternalResources(r.ir, v)
+	for _, err := range errs {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+		})
 	}
-	for _, v := range v.Output {
-		if !v.Internal && len(v.Resource.Raw) != 0 {
-			gvk := r.getgvk(oc, v.Resource)
-			r.addGvk(gvk)
-		}
+	for _, fr := range frs {
+		r.addGvk(oc, fr.GVK, fr.Access, fr.Internal)
 	}
 }
 
 func (r *er) getgvk(oc *OriginContext, v runtime.RawExtension) *schema.GroupVersionKind {
-	gvk, err := meta.GetGVKFromRuntimeRawExtension(v)
+	gvk, err := r.ir.GetGVK(v)
 	if err != nil {
 		r.recordResult(Result{
 			OriginContext: oc,
 			Error:         err.Error(),
+			Code:          CodeInvalidGVK,
 		})
 	}
 	return gvk
@@ -0,0 +1,193 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// inputAccessConfig supplies a resource as input to a function of every type
+// that takes an input, every function uses a different kind
+const inputAccessConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+pipelines:
+- name: apply
+  tasks:
+    query:
+      type: query
+      input:
+        resource:
+          apiVersion: v1
+          kind: Secret
+    template:
+      type: gotemplate
+      input:
+        resource:
+          apiVersion: apps/v1
+          kind: Deployment
+    jq:
+      type: jq
+      input:
+        expression: $pod.metadata.name
+        resource:
+          apiVersion: v1
+          kind: ConfigMap
+    slice:
+      type: slice
+      input:
+        value: $pod.metadata.name
+        resource:
+          apiVersion: v1
+          kind: Service
+    map:
+      type: map
+      input:
+        key: $pod.metadata.name
+        value: $pod.metadata.name
+        resource:
+          apiVersion: v1
+          kind: Namespace
+- name: delete
+`
+
+func TestExternalResourceInputAccess(t *testing.T) {
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal([]byte(inputAccessConfig), cfg); err != nil {
+		t.Fatal(err)
+	}
+	// the resources are collected independent of the validation of the inputs
+	p, _ := NewParser("inputaccess", cfg)
+	ers, result := p.GetExternalResourceUsages()
+	if len(result) != 0 {
+		t.Fatalf("unexpected results: %v", result)
+	}
+	got := map[string][]ResourceAccess{}
+	for _, er := range ers {
+		for _, u := range er.Usages {
+			got[er.GVK.Kind] = append(got[er.GVK.Kind], u.Access)
+		}
+	}
+
+	cases := map[string]ResourceAccess{
+		"Secret":     ResourceAccessRead,
+		"Deployment": ResourceAccessWrite,
+		"ConfigMap":  "",
+		"Service":    "",
+		"Namespace":  "",
+	}
+	for kind, want := range cases {
+		t.Run(kind, func(t *testing.T) {
+			if want == "" {
+				if len(got[kind]) != 0 {
+					t.Errorf("%s is not accessed in the api server, got: %v", kind, got[kind])
+				}
+				return
+			}
+			if len(got[kind]) != 1 || got[kind][0] != want {
+				t.Errorf("got %v, want %s", got[kind], want)
+			}
+		})
+	}
+}
+
+func TestFunctionInputAccess(t *testing.T) {
+	cases := map[ctrlcfgv1alpha1.FunctionType]ResourceAccess{
+		ctrlcfgv1alpha1.QueryType:      ResourceAccessRead,
+		ctrlcfgv1alpha1.GoTemplateType: ResourceAccessWrite,
+		ctrlcfgv1alpha1.SliceType:      "",
+		ctrlcfgv1alpha1.MapType:        "",
+		ctrlcfgv1alpha1.JQType:         "",
+		ctrlcfgv1alpha1.BlockType:      "",
+	}
+	for fnType, want := range cases {
+		t.Run(string(fnType), func(t *testing.T) {
+			frs, errs := getFunctionTypeHandler(fnType).ExternalResources(newConfigIR(&ctrlcfgv1alpha1.ControllerConfigSpec{}), &ctrlcfgv1alpha1.Function{
+				Type: fnType,
+				Input: &ctrlcfgv1alpha1.Input{
+					Resource: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Pod"}`)},
+				},
+			})
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			if want == "" {
+				if len(frs) != 0 {
+					t.Errorf("the input of %s is not accessed in the api server, got: %v", fnType, frs[0].Access)
+				}
+				return
+			}
+			if len(frs) != 1 || frs[0].Access != want {
+				t.Errorf("got %v, want 1 resource with access %s", frs, want)
+			}
+		})
+	}
+}
+
+func TestGoTemplateOutputAccess(t *testing.T) {
+	frs, errs := getFunctionTypeHandler(ctrlcfgv1alpha1.GoTemplateType).ExternalResources(newConfigIR(&ctrlcfgv1alpha1.ControllerConfigSpec{}), &ctrlcfgv1alpha1.Function{
+		Type: ctrlcfgv1alpha1.GoTemplateType,
+		Input: &ctrlcfgv1alpha1.Input{
+			Resource: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Pod"}`)},
+		},
+		Output: map[string]*ctrlcfgv1alpha1.Output{
+			"cm": {Resource: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap"}`)}},
+		},
+	})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	// the input is rendered in the output and not written itself
+	if len(frs) != 1 || frs[0].GVK.Kind != "ConfigMap" || frs[0].Access != ResourceAccessWrite {
+		t.Errorf("got %v, want the ConfigMap output written", frs)
+	}
+}
+
+func TestUnknownFunctionType(t *testing.T) {
+	const want = "unknown function type unknown"
+	v := &ctrlcfgv1alpha1.Function{
+		Type: "unknown",
+		Input: &ctrlcfgv1alpha1.Input{
+			Resource: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Pod"}`)},
+		},
+	}
+	h := getFunctionTypeHandler(v.Type)
+	ir := newConfigIR(&ctrlcfgv1alpha1.ControllerConfigSpec{})
+	if h.Contract() != nil {
+		t.Errorf("got contract %v, want none", h.Contract())
+	}
+	_, outErrs := h.Outputs(ir, "fn", v)
+	_, resErrs := h.ExternalResources(ir, v)
+	for name, errs := range map[string][]error{
+		"Validate":          h.Validate(v),
+		"Outputs":           outErrs,
+		"ExternalResources": resErrs,
+	} {
+		if len(errs) != 1 || errs[0].Error() != want {
+			t.Errorf("%s: got %v, want %s", name, errs, want)
+		}
+	}
+}
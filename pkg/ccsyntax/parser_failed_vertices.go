@@ -0,0 +1,95 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"strings"
+	"sync"
+)
+
+// failedVertices records the root vertices and vertices for which a parse
+// phase reported a result, the next phases skip them such that they can run
+// on a partial graph
+type failedVertices struct {
+	m        sync.RWMutex
+	roots    map[string]struct{}
+	vertices map[string]struct{}
+}
+
+func newFailedVertices() *failedVertices {
+	return &failedVertices{
+		roots:    map[string]struct{}{},
+		vertices: map[string]struct{}{},
+	}
+}
+
+func (r *failedVertices) add(results []Result) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	for _, result := range results {
+		oc := result.OriginContext
+		if oc == nil {
+			continue
+		}
+		switch oc.Origin {
+		case OriginFow:
+			r.roots[rootKey(oc)] = struct{}{}
+		case OriginVariable, OriginFunction:
+			r.vertices[vertexKey(oc, oc.VertexName)] = struct{}{}
+		}
+	}
+}
+
+// has returns true if the root vertex or the vertex of the origin context failed
+func (r *failedVertices) has(oc *OriginContext) bool {
+	if r == nil {
+		return false
+	}
+	r.m.RLock()
+	defer r.m.RUnlock()
+	if len(r.roots) == 0 && len(r.vertices) == 0 {
+		return false
+	}
+	if _, ok := r.roots[rootKey(oc)]; ok {
+		return true
+	}
+	_, ok := r.vertices[vertexKey(oc, oc.VertexName)]
+	return ok
+}
+
+// hasVertex returns true if the vertex with the supplied name in the root
+// vertex of the origin context failed
+func (r *failedVertices) hasVertex(oc *OriginContext, vertexName string) bool {
+	if r == nil {
+		return false
+	}
+	r.m.RLock()
+	defer r.m.RUnlock()
+	if len(r.vertices) == 0 {
+		return false
+	}
+	_, ok := r.vertices[vertexKey(oc, vertexName)]
+	return ok
+}
+
+func rootKey(oc *OriginContext) string {
+	return strings.Join([]string{string(oc.FOWS), oc.RootVertexName}, "/")
+}
+
+func vertexKey(oc *OriginContext, vertexName string) string {
+	return strings.Join([]string{string(oc.FOWS), oc.RootVertexName, vertexName}, "/")
+}
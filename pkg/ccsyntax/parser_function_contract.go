@@ -0,0 +1,118 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"strings"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+)
+
+// InputField is a field of the input section of a function
+type InputField string
+
+const (
+	InputFieldKey           InputField = "key"
+	InputFieldValue         InputField = "value"
+	InputFieldExpression    InputField = "expression"
+	InputFieldResource      InputField = "resource"
+	InputFieldTemplate      InputField = "template"
+	InputFieldSelector      InputField = "selector"
+	InputFieldNamespace     InputField = "namespace"
+	InputFieldFieldSelector InputField = "fieldSelector"
+	InputFieldGenericInput  InputField = "generic input"
+)
+
+var inputFields = []InputField{
+	InputFieldKey,
+	InputFieldValue,
+	InputFieldExpression,
+	InputFieldResource,
+	InputFieldTemplate,
+	InputFieldSelector,
+	InputFieldNamespace,
+	InputFieldFieldSelector,
+	InputFieldGenericInput,
+}
+
+// FunctionContract defines the input and output a function type accepts, it
+// is provided by the FunctionTypeHandler of the function type
+type FunctionContract struct {
+	// InputRequired requires an input section
+	InputRequired bool
+	// Required lists the input fields that need to be present, every entry
+	// is a set of alternatives of which at least 1 needs to be present
+	Required [][]InputField
+	// Allowed lists the optional input fields, the required fields are
+	// always allowed
+	Allowed []InputField
+	// OutputRequired requires at least 1 output, functions without output
+	// have no effect
+	OutputRequired bool
+}
+
+func (r *FunctionContract) isAllowed(f InputField) bool {
+	for _, alternatives := range r.Required {
+		for _, required := range alternatives {
+			if f == required {
+				return true
+			}
+		}
+	}
+	for _, allowed := range r.Allowed {
+		if f == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// hasInputField returns true if the input field is present
+func hasInputField(v *ctrlcfgv1alpha1.Input, f InputField) bool {
+	if v == nil {
+		return false
+	}
+	switch f {
+	case InputFieldKey:
+		return v.Key != ""
+	case InputFieldValue:
+		return v.Value != ""
+	case InputFieldExpression:
+		return v.Expression != ""
+	case InputFieldResource:
+		return len(v.Resource.Raw) != 0
+	case InputFieldTemplate:
+		return v.Template != ""
+	case InputFieldSelector:
+		return v.Selector != nil
+	case InputFieldNamespace:
+		return v.Namespace != ""
+	case InputFieldFieldSelector:
+		return v.FieldSelector != ""
+	case InputFieldGenericInput:
+		return len(v.GenericInput) != 0
+	}
+	return false
+}
+
+func joinInputFields(fields []InputField) string {
+	s := make([]string, 0, len(fields))
+	for _, f := range fields {
+		s = append(s, string(f))
+	}
+	return strings.Join(s, " or ")
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestFunctionContracts(t *testing.T) {
+	// the contracts are taken from the registered function type handlers,
+	// the test asserts that the validation enforces them
+	mft.RLock()
+	fnTypes := make([]ctrlcfgv1alpha1.FunctionType, 0, len(functionTypeHandlers))
+	for fnType := range functionTypeHandlers {
+		fnTypes = append(fnTypes, fnType)
+	}
+	mft.RUnlock()
+	for _, fnType := range fnTypes {
+		contract := getFunctionTypeHandler(fnType).Contract()
+		if contract == nil {
+			t.Errorf("no contract for %s", fnType)
+			continue
+		}
+		tc := *contract
+		t.Run(string(fnType), func(t *testing.T) {
+			// a function with the required and allowed input and an output
+			// is valid
+			valid := func() *ctrlcfgv1alpha1.Function {
+				v := &ctrlcfgv1alpha1.Function{
+					Type:  fnType,
+					Input: &ctrlcfgv1alpha1.Input{},
+					Output: map[string]*ctrlcfgv1alpha1.Output{
+						"out": {Resource: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Pod"}`)}},
+					},
+				}
+				for _, alternatives := range tc.Required {
+					setInputField(v.Input, alternatives[0])
+				}
+				for _, f := range tc.Allowed {
+					setInputField(v.Input, f)
+				}
+				return v
+			}
+			if result := validateContract(valid()); len(result) != 0 {
+				t.Errorf("unexpected results: %v", result)
+			}
+
+			// every alternative of a required field satisfies the contract
+			for _, alternatives := range tc.Required {
+				for _, f := range alternatives {
+					v := valid()
+					for _, alternative := range alternatives {
+						unsetInputField(v.Input, alternative)
+					}
+					setInputField(v.Input, f)
+					if result := validateContract(v); len(result) != 0 {
+						t.Errorf("%s: unexpected results: %v", f, result)
+					}
+				}
+				v := valid()
+				for _, f := range alternatives {
+					unsetInputField(v.Input, f)
+				}
+				want := fmt.Sprintf("%s needs to be present in %s", joinInputFields(alternatives), fnType)
+				if result := validateContract(v); !hasError(result, want) {
+					t.Errorf("missing %s: got %v, want %s", joinInputFields(alternatives), result, want)
+				}
+			}
+
+			// the fields that are neither required nor allowed are rejected
+			for _, f := range inputFields {
+				if isContractField(tc.Required, tc.Allowed, f) {
+					continue
+				}
+				v := valid()
+				setInputField(v.Input, f)
+				want := fmt.Sprintf("%s is not allowed in the input of %s", f, fnType)
+				if result := validateContract(v); !hasError(result, want) {
+					t.Errorf("%s: got %v, want %s", f, result, want)
+				}
+			}
+
+			v := valid()
+			v.Input = nil
+			want := fmt.Sprintf("input is needed in a function %s", fnType)
+			if got := hasError(validateContract(v), want); got != tc.InputRequired {
+				t.Errorf("without input: got input needed %t, want %t", got, tc.InputRequired)
+			}
+
+			v = valid()
+			v.Output = nil
+			want = fmt.Sprintf("output needs to be present in %s", fnType)
+			if got := hasError(validateContract(v), want); got != tc.OutputRequired {
+				t.Errorf("without output: got output needed %t, want %t", got, tc.OutputRequired)
+			}
+		})
+	}
+}
+
+// customFunctionType is a function type registered outside of the builtin
+// function types
+type customFunctionType struct {
+	baseFunctionType
+}
+
+func TestRegisteredFunctionContract(t *testing.T) {
+	const fnType ctrlcfgv1alpha1.FunctionType = "contract-test"
+	if _, ok := GetFunctionTypeHandler(fnType); !ok {
+		if err := RegisterFunctionType(fnType, &customFunctionType{baseFunctionType{contract: &FunctionContract{
+			InputRequired: true,
+			Required:      [][]InputField{{InputFieldExpression}},
+		}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, tc := range map[string]struct {
+		input *ctrlcfgv1alpha1.Input
+		want  string
+	}{
+		"valid":      {input: &ctrlcfgv1alpha1.Input{Expression: "$a"}},
+		"noInput":    {want: "input is needed in a function contract-test"},
+		"missing":    {input: &ctrlcfgv1alpha1.Input{}, want: "expression needs to be present in contract-test"},
+		"notAllowed": {input: &ctrlcfgv1alpha1.Input{Expression: "$a", Key: "$b"}, want: "key is not allowed in the input of contract-test"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := validateContract(&ctrlcfgv1alpha1.Function{Type: fnType, Input: tc.input})
+			if tc.want == "" {
+				if len(result) != 0 {
+					t.Errorf("unexpected results: %v", result)
+				}
+				return
+			}
+			if !hasError(result, tc.want) {
+				t.Errorf("got %v, want %s", result, tc.want)
+			}
+		})
+	}
+}
+
+func validateContract(v *ctrlcfgv1alpha1.Function) []Result {
+	r := &vs{ir: newConfigIR(&ctrlcfgv1alpha1.ControllerConfigSpec{})}
+	r.validateContract(&OriginContext{VertexName: "fn"}, v)
+	return r.result
+}
+
+func hasError(result []Result, msg string) bool {
+	for _, r := range result {
+		if r.Error == msg {
+			return true
+		}
+	}
+	return false
+}
+
+func isContractField(required [][]InputField, allowed []InputField, f InputField) bool {
+	for _, alternatives := range required {
+		for _, alternative := range alternatives {
+			if f == alternative {
+				return true
+			}
+		}
+	}
+	for _, a := range allowed {
+		if f == a {
+			return true
+		}
+	}
+	return false
+}
+
+func setInputField(v *ctrlcfgv1alpha1.Input, f InputField) {
+	switch f {
+	case InputFieldKey:
+		v.Key = "$a"
+	case InputFieldValue:
+		v.Value = "$a"
+	case InputFieldExpression:
+		v.Expression = "$a"
+	case InputFieldResource:
+		v.Resource = runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Pod"}`)}
+	case InputFieldTemplate:
+		v.Template = "{{ .a }}"
+	case InputFieldSelector:
+		v.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"a": "$a"}}
+	case InputFieldNamespace:
+		v.Namespace = "$a"
+	case InputFieldFieldSelector:
+		v.FieldSelector = "metadata.name=$a"
+	case InputFieldGenericInput:
+		v.GenericInput = map[string]string{"a": "$a"}
+	}
+}
+
+func unsetInputField(v *ctrlcfgv1alpha1.Input, f InputField) {
+	switch f {
+	case InputFieldKey:
+		v.Key = ""
+	case InputFieldValue:
+		v.Value = ""
+	case InputFieldExpression:
+		v.Expression = ""
+	case InputFieldResource:
+		v.Resource = runtime.RawExtension{}
+	case InputFieldTemplate:
+		v.Template = ""
+	case InputFieldSelector:
+		v.Selector = nil
+	case InputFieldNamespace:
+		v.Namespace = ""
+	case InputFieldFieldSelector:
+		v.FieldSelector = ""
+	case InputFieldGenericInput:
+		v.GenericInput = nil
+	}
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"sync"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// FunctionTypeHandler implements the behavior of a function type. The
+// builtin function types are registered by default, additional function
+// types can be registered with RegisterFunctionType.
+type FunctionTypeHandler interface {
+	// Validate validates the type specific fields of the function
+	Validate(v *ctrlcfgv1alpha1.Function) []error
+	// Outputs returns the outputs of the function, a function without output
+	// section stores its result in a variable named after the vertex. The gvks
+	// are looked up in the decoded config.
+	Outputs(ir ConfigIR, vertexName string, v *ctrlcfgv1alpha1.Function) ([]*FunctionOutput, []error)
+	// References returns the expressions of the function that can reference
+	// variables
+	References(v *ctrlcfgv1alpha1.Function) []string
+	// ExternalResources returns the resources the function accesses through
+	// the api server, the gvks are looked up in the decoded config
+	ExternalResources(ir ConfigIR, v *ctrlcfgv1alpha1.Function) ([]*FunctionResource, []error)
+	// Images returns the images and exec commands the function runs
+	Images(v *ctrlcfgv1alpha1.Function) []*FunctionImage
+	// Contract returns the input and output the function type accepts, a
+	// function type without contract accepts any input and output
+	Contract() *FunctionContract
+}
+
+// FunctionOutput is an output variable of a function
+type FunctionOutput struct {
+	VarName     string
+	Internal    bool
+	Conditioned bool
+	GVK         *schema.GroupVersionKind
+}
+
+// FunctionResource is a resource a function accesses
+type FunctionResource struct {
+	GVK      *schema.GroupVersionKind
+	Access   ResourceAccess
+	Internal bool
+}
+
+// FunctionImage is an image or exec command a function runs
+type FunctionImage struct {
+	Name string
+	Kind ExecutorKind
+}
+
+var (
+	mft                  sync.RWMutex
+	functionTypeHandlers = map[ctrlcfgv1alpha1.FunctionType]FunctionTypeHandler{
+		ctrlcfgv1alpha1.QueryType: &queryFunctionType{baseFunctionType{contract: &FunctionContract{
+			InputRequired: true,
+			Required:      [][]InputField{{InputFieldResource}},
+			Allowed:       []InputField{InputFieldSelector, InputFieldNamespace, InputFieldFieldSelector},
+		}}},
+		ctrlcfgv1alpha1.SliceType: &baseFunctionType{contract: &FunctionContract{
+			InputRequired: true,
+			Required:      [][]InputField{{InputFieldValue}},
+		}},
+		ctrlcfgv1alpha1.MapType: &baseFunctionType{contract: &FunctionContract{
+			InputRequired: true,
+			Required:      [][]InputField{{InputFieldKey}, {InputFieldValue}},
+		}},
+		ctrlcfgv1alpha1.JQType: &baseFunctionType{contract: &FunctionContract{
+			InputRequired: true,
+			Required:      [][]InputField{{InputFieldExpression}},
+		}},
+		ctrlcfgv1alpha1.BlockType: &baseFunctionType{contract: &FunctionContract{}},
+		ctrlcfgv1alpha1.GoTemplateType: &goTemplateFunctionType{baseFunctionType{contract: &FunctionContract{
+			InputRequired: true,
+			Required:      [][]InputField{{InputFieldResource, InputFieldTemplate}},
+			Allowed:       []InputField{InputFieldGenericInput},
+		}}},
+		ctrlcfgv1alpha1.ContainerType: newExecutorFunctionType(ctrlcfgv1alpha1.ContainerType, ExecutorKindFunction),
+		ctrlcfgv1alpha1.WasmType:      newExecutorFunctionType(ctrlcfgv1alpha1.WasmType, ExecutorKindWasm),
+		ctrlcfgv1alpha1.ServiceCallType: &serviceCallFunctionType{baseFunctionType{contract: &FunctionContract{
+			Allowed:        []InputField{InputFieldGenericInput},
+			OutputRequired: true,
+		}}},
+	}
+)
+
+// RegisterFunctionType registers the handler of a function type, registering
+// a function type that is already registered fails
+func RegisterFunctionType(t ctrlcfgv1alpha1.FunctionType, h FunctionTypeHandler) error {
+	if t == "" || h == nil {
+		return fmt.Errorf("cannot register a function type without name or handler")
+	}
+	mft.Lock()
+	defer mft.Unlock()
+	if _, ok := functionTypeHandlers[t]; ok {
+		return fmt.Errorf("function type %s is already registered", t)
+	}
+	functionTypeHandlers[t] = h
+	return nil
+}
+
+// GetFunctionTypeHandler returns the handler of a function type
+func GetFunctionTypeHandler(t ctrlcfgv1alpha1.FunctionType) (FunctionTypeHandler, bool) {
+	mft.RLock()
+	defer mft.RUnlock()
+	h, ok := functionTypeHandlers[t]
+	return h, ok
+}
+
+// getFunctionTypeHandler returns the handler of the function type, the
+// handler of an unknown function type reports the unknown type
+func getFunctionTypeHandler(t ctrlcfgv1alpha1.FunctionType) FunctionTypeHandler {
+	if h, ok := GetFunctionTypeHandler(t); ok {
+		return h
+	}
+	return &unknownFunctionType{t: t}
+}
+
+// unknownFunctionType is the handler of a function type that is not
+// registered, it has no contract and fails validation, outputs and resources
+type unknownFunctionType struct {
+	baseFunctionType
+	t ctrlcfgv1alpha1.FunctionType
+}
+
+func (r *unknownFunctionType) err() error {
+	return fmt.Errorf("unknown function type %s", r.t)
+}
+
+func (r *unknownFunctionType) Validate(v *ctrlcfgv1alpha1.Function) []error {
+	return []error{r.err()}
+}
+
+func (r *unknownFunctionType) Outputs(ir ConfigIR, vertexName string, v *ctrlcfgv1alpha1.Function) ([]*FunctionOutput, []error) {
+	return nil, []error{r.err()}
+}
+
+func (r *unknownFunctionType) ExternalResources(ir ConfigIR, v *ctrlcfgv1alpha1.Function) ([]*FunctionResource, []error) {
+	return nil, []error{r.err()}
+}
+
+// baseFunctionType implements the behavior shared by the function types, the
+// result of a function without output is an internal variable
+type baseFunctionType struct {
+	contract *FunctionContract
+}
+
+func (r *baseFunctionType) Contract() *FunctionContract {
+	return r.contract
+}
+
+func (r *baseFunctionType) Validate(v *ctrlcfgv1alpha1.Function) []error {
+	return nil
+}
+
+func (r *baseFunctionType) Outputs(ir ConfigIR, vertexName string, v *ctrlcfgv1alpha1.Function) ([]*FunctionOutput, []error) {
+	if len(v.Output) != 0 {
+		return getOutputs(ir, v)
+	}
+	o := &FunctionOutput{VarName: vertexName, Internal: true}
+	if v.Input != nil && len(v.Input.Resource.Raw) != 0 {
+		gvk, err := ir.GetGVK(v.Input.Resource)
+		if err != nil {
+			return []*FunctionOutput{o}, []error{err}
+		}
+		o.GVK = gvk
+	}
+	return []*FunctionOutput{o}, nil
+}
+
+func (r *baseFunctionType) References(v *ctrlcfgv1alpha1.Function) []string {
+	refs := []string{}
+	if v.Input == nil {
+		return refs
+	}
+	if v.Input.Selector != nil {
+		for _, k := range sortedKeys(v.Input.Selector.MatchLabels) {
+			refs = append(refs, k, v.Input.Selector.MatchLabels[k])
+		}
+		for _, req := range v.Input.Selector.MatchExpressions {
+			refs = append(refs, req.Key)
+			refs = append(refs, req.Values...)
+		}
+	}
+	if v.Input.Namespace != "" {
+		refs = append(refs, v.Input.Namespace)
+	}
+	// the references in a field selector are terminated by the separators
+	refs = append(refs, fieldSelectorReference.FindAllString(v.Input.FieldSelector, -1)...)
+	if v.Input.Key != "" {
+		refs = append(refs, v.Input.Key)
+	}
+	if v.Input.Value != "" {
+		refs = append(refs, v.Input.Value)
+	}
+	if v.Input.Expression != "" {
+		refs = append(refs, v.Input.Expression)
+	}
+	for _, k := range sortedKeys(v.Input.GenericInput) {
+		refs = append(refs, v.Input.GenericInput[k])
+	}
+	return refs
+}
+
+// ExternalResources returns the resources in the output section, only a query
+// reads its input from the api server
+func (r *baseFunctionType) ExternalResources(ir ConfigIR, v *ctrlcfgv1alpha1.Function) ([]*FunctionResource, []error) {
+	return getResources(ir, v, "")
+}
+
+func (r *baseFunctionType) Images(v *ctrlcfgv1alpha1.Function) []*FunctionImage {
+	return nil
+}
+
+// queryFunctionType reads the resource in the input from the api server
+type queryFunctionType struct {
+	baseFunctionType
+}
+
+func (r *queryFunctionType) ExternalResources(ir ConfigIR, v *ctrlcfgv1alpha1.Function) ([]*FunctionResource, []error) {
+	return getResources(ir, v, ResourceAccessRead)
+}
+
+// goTemplateFunctionType renders the resource in the input in the api server
+// when no output is defined
+type goTemplateFunctionType struct {
+	baseFunctionType
+}
+
+func (r *goTemplateFunctionType) Outputs(ir ConfigIR, vertexName string, v *ctrlcfgv1alpha1.Function) ([]*FunctionOutput, []error) {
+	if len(v.Output) != 0 {
+		return getOutputs(ir, v)
+	}
+	if v.Input == nil || len(v.Input.Resource.Raw) == 0 {
+		// TODO what to do for a template ??? How do i get a GVK, is it also an external resource
+		return nil, nil
+	}
+	gvk, err := ir.GetGVK(v.Input.Resource)
+	if err != nil {
+		return []*FunctionOutput{{VarName: vertexName}}, []error{err}
+	}
+	return []*FunctionOutput{{VarName: vertexName, GVK: gvk}}, nil
+}
+
+// ExternalResources returns the resource in the input as written to the api
+// server when no output is defined, otherwise the outputs are written
+func (r *goTemplateFunctionType) ExternalResources(ir ConfigIR, v *ctrlcfgv1alpha1.Function) ([]*FunctionResource, []error) {
+	if len(v.Output) != 0 {
+		return getResources(ir, v, "")
+	}
+	return getResources(ir, v, ResourceAccessWrite)
+}
+
+// executorFunctionType runs an image or exec command
+type executorFunctionType struct {
+	baseFunctionType
+	t    ctrlcfgv1alpha1.FunctionType
+	kind ExecutorKind
+}
+
+func newExecutorFunctionType(t ctrlcfgv1alpha1.FunctionType, kind ExecutorKind) *executorFunctionType {
+	return &executorFunctionType{
+		baseFunctionType: baseFunctionType{contract: &FunctionContract{
+			Allowed:        []InputField{InputFieldGenericInput},
+			OutputRequired: true,
+		}},
+		t:    t,
+		kind: kind,
+	}
+}
+
+func (r *executorFunctionType) Validate(v *ctrlcfgv1alpha1.Function) []error {
+	return validateExecutor(r.t, &v.Executor)
+}
+
+func (r *executorFunctionType) Images(v *ctrlcfgv1alpha1.Function) []*FunctionImage {
+	images := []*FunctionImage{}
+	if v.Exec != "" {
+		images = append(images, &FunctionImage{Name: v.Exec, Kind: ExecutorKindExec})
+	}
+	if v.Image != "" {
+		images = append(images, &FunctionImage{Name: v.Image, Kind: r.kind})
+	}
+	return images
+}
+
+// serviceCallFunctionType consumes the output of a service
+type serviceCallFunctionType struct {
+	baseFunctionType
+}
+
+func (r *serviceCallFunctionType) Validate(v *ctrlcfgv1alpha1.Function) []error {
+	if v.ServiceRef == nil || v.ServiceRef.Name == "" || v.ServiceRef.Output == "" {
+		return []error{fmt.Errorf("a serviceRef with name and output needs to be present in %s", v.Type)}
+	}
+	return nil
+}
+
+// getOutputs returns the outputs of the output section sorted by variable name
+func getOutputs(ir ConfigIR, v *ctrlcfgv1alpha1.Function) ([]*FunctionOutput, []error) {
+	outputs := []*FunctionOutput{}
+	errs := []error{}
+	for _, varName := range sortedKeys(v.Output) {
+		outputCfg := v.Output[varName]
+		if outputCfg == nil {
+			continue
+		}
+		gvk, err := ir.GetGVK(outputCfg.Resource)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		outputs = append(outputs, &FunctionOutput{
+			VarName:     varName,
+			Internal:    outputCfg.Internal,
+			Conditioned: outputCfg.Conditioned,
+			GVK:         gvk,
+		})
+	}
+	return outputs, errs
+}
+
+// getResources returns the resource in the input with the supplied access and
+// the resources in the output section which are written, the input is skipped
+// when no access is supplied
+func getResources(ir ConfigIR, v *ctrlcfgv1alpha1.Function, inputAccess ResourceAccess) ([]*FunctionResource, []error) {
+	resources := []*FunctionResource{}
+	errs := []error{}
+	if inputAccess != "" && v.Input != nil && len(v.Input.Resource.Raw) != 0 {
+		gvk, err := ir.GetGVK(v.Input.Resource)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		resources = append(resources, &FunctionResource{GVK: gvk, Access: inputAccess})
+	}
+	for _, varName := range sortedKeys(v.Output) {
+		outputCfg := v.Output[varName]
+		if outputCfg == nil || len(outputCfg.Resource.Raw) == 0 {
+			continue
+		}
+		gvk, err := ir.GetGVK(outputCfg.Resource)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		resources = append(resources, &FunctionResource{GVK: gvk, Access: ResourceAccessWrite, Internal: outputCfg.Internal})
+	}
+	return resources, errs
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// FuzzParse parses arbitrary configs. On a single cpu the execs pause while
+// a new input is minimized, -fuzzminimizetime bounds the pause.
+func FuzzParse(f *testing.F) {
+	configs, err := filepath.Glob(filepath.Join("testdata", "*", "config.yaml"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, config := range configs {
+		b, err := os.ReadFile(config)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	// a for with an apply and delete pipeline to which the fuzzer can add
+	// tasks
+	const forPipelines = "for:\n  a:\n    resource:\n      apiVersion: v1\n      kind: Pod\n    applyPipelineRef: p\n    deletePipelineRef: d\npipelines:\n- name: d\n"
+	for _, s := range []string{
+		forPipelines,
+		forPipelines + "- name: p\n  tasks:\n    t:\n      type: jq\n",
+		forPipelines + "- name: p\n  tasks:\n    t:\n      type: jq\n      input:\n        expression: $\n",
+		forPipelines + "- name: p\n  tasks:\n    t:\n      type: query\n      dependsOn: [x]\n",
+		forPipelines + "- name: p\n  tasks:\n    t:\n      type: jq\n      input:\n        expression: $t\n",
+		forPipelines + "- name: p\n  tasks:\n    t: null\n",
+		forPipelines + "- name: p\n  tasks:\n    t:\n      type: block\n      block:\n        u:\n          type: jq\n",
+		forPipelines + "- name: p\n  tasks:\n    t:\n      type: container\n      output:\n        o: null\n",
+		forPipelines + "- name: p\nservices:\n  s: null\n",
+	} {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+		if err := yaml.Unmarshal(b, cfg); err != nil {
+			return
+		}
+		p, _ := NewParser("fuzz", cfg)
+		p.Parse()
+		p.ParseAll()
+	})
+}
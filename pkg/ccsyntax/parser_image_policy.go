@@ -0,0 +1,107 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"strings"
+)
+
+const defaultTag = "latest"
+
+// ImagePolicy defines the rules the image references in the controller
+// config have to comply with
+type ImagePolicy struct {
+	// AllowedRegistries lists the registries images can be pulled from.
+	// An entry is either a registry, e.g. europe-docker.pkg.dev, or a
+	// registry with a repository prefix, e.g. europe-docker.pkg.dev/srlinux.
+	// When empty all registries are allowed.
+	AllowedRegistries []string `json:"allowedRegistries,omitempty" yaml:"allowedRegistries,omitempty"`
+	// ForbiddenTags lists the tags that cannot be used. An image without tag
+	// and digest resolves to the latest tag.
+	ForbiddenTags []string `json:"forbiddenTags,omitempty" yaml:"forbiddenTags,omitempty"`
+	// RequireDigest requires every image to be pinned by digest
+	RequireDigest bool `json:"requireDigest,omitempty" yaml:"requireDigest,omitempty"`
+	// Severity of the policy violations, defaults to error
+	Severity Severity `json:"severity,omitempty" yaml:"severity,omitempty"`
+}
+
+// ValidateImages validates the image references of the functions and services
+// against the image policy. Malformed image references are always reported
+// as an error, policy violations with the severity of the policy.
+func (r *parser) ValidateImages(policy *ImagePolicy) []Result {
+	inv, result := r.GetImageInventory()
+	for i := range result {
+		result[i].Severity = SeverityError
+	}
+	if policy == nil {
+		return result
+	}
+
+	severity := policy.Severity
+	if severity == "" {
+		severity = SeverityError
+	}
+	for _, image := range inv.Images {
+		// exec executors are not image references
+		// malformed references are already reported by the inventory
+		if image.Kind == ExecutorKindExec || image.Registry == "" {
+			continue
+		}
+		for _, err := range policy.validate(image) {
+			for _, oc := range image.Usages {
+				result = append(result, Result{
+					OriginContext: oc,
+					Error:         err.Error(),
+					Severity:      severity,
+				})
+			}
+		}
+	}
+	return DeduplicateResults(result)
+}
+
+func (r *ImagePolicy) validate(image *ImageInfo) []error {
+	errs := []error{}
+	if len(r.AllowedRegistries) > 0 && !r.isRegistryAllowed(image) {
+		errs = append(errs, fmt.Errorf("image %s uses registry %s which is not allowed", image.Name, image.Registry))
+	}
+	if r.RequireDigest && image.Digest == "" {
+		errs = append(errs, fmt.Errorf("image %s must be pinned by digest", image.Name))
+	}
+	tag := image.Tag
+	if tag == "" && image.Digest == "" {
+		tag = defaultTag
+	}
+	for _, forbiddenTag := range r.ForbiddenTags {
+		if tag == forbiddenTag {
+			errs = append(errs, fmt.Errorf("image %s uses forbidden tag %s", image.Name, tag))
+		}
+	}
+	return errs
+}
+
+func (r *ImagePolicy) isRegistryAllowed(image *ImageInfo) bool {
+	name := image.Registry + "/" + image.Repository
+	for _, allowed := range r.AllowedRegistries {
+		allowed = strings.TrimSuffix(allowed, "/")
+		if image.Registry == allowed || strings.HasPrefix(name, allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// imageConfig runs a container function, the image is supplied by the test
+const imageConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+pipelines:
+- name: apply
+  tasks:
+    fn:
+      type: container
+      image: %s
+      output:
+        nodes:
+          internal: true
+          resource:
+            apiVersion: topo.yndd.io/v1alpha1
+            kind: Node
+- name: delete
+`
+
+func TestImagePolicy(t *testing.T) {
+	cases := map[string]struct {
+		image  string
+		policy ImagePolicy
+		want   []string
+	}{
+		"AllowedRegistry": {
+			image:  "europe-docker.pkg.dev/srlinux/fn:v1",
+			policy: ImagePolicy{AllowedRegistries: []string{"europe-docker.pkg.dev"}},
+		},
+		"AllowedRepositoryPrefix": {
+			image:  "europe-docker.pkg.dev/srlinux/fn:v1",
+			policy: ImagePolicy{AllowedRegistries: []string{"europe-docker.pkg.dev/srlinux/"}},
+		},
+		"PartialRepositoryPrefix": {
+			image:  "europe-docker.pkg.dev/srlinux/fn:v1",
+			policy: ImagePolicy{AllowedRegistries: []string{"europe-docker.pkg.dev/srl"}},
+			want:   []string{"image europe-docker.pkg.dev/srlinux/fn:v1 uses registry europe-docker.pkg.dev which is not allowed"},
+		},
+		"NormalizedRegistry": {
+			image:  "nginx:1.25",
+			policy: ImagePolicy{AllowedRegistries: []string{"europe-docker.pkg.dev"}},
+			want:   []string{"image nginx:1.25 uses registry docker.io which is not allowed"},
+		},
+		"ForbiddenTag": {
+			image:  "fn:dev",
+			policy: ImagePolicy{ForbiddenTags: []string{"latest", "dev"}},
+			want:   []string{"image fn:dev uses forbidden tag dev"},
+		},
+		"ImplicitLatest": {
+			image:  "fn",
+			policy: ImagePolicy{ForbiddenTags: []string{"latest"}},
+			want:   []string{"image fn uses forbidden tag latest"},
+		},
+		"DigestWithoutTag": {
+			image:  "fn@sha256:" + strings.Repeat("a", 64),
+			policy: ImagePolicy{ForbiddenTags: []string{"latest"}},
+		},
+		"RequireDigest": {
+			image:  "fn:v1",
+			policy: ImagePolicy{RequireDigest: true},
+			want:   []string{"image fn:v1 must be pinned by digest"},
+		},
+		"Digest": {
+			image:  "fn:v1@sha256:" + strings.Repeat("a", 64),
+			policy: ImagePolicy{RequireDigest: true, ForbiddenTags: []string{"latest"}},
+		},
+		"AllViolations": {
+			image: "nginx",
+			policy: ImagePolicy{
+				AllowedRegistries: []string{"europe-docker.pkg.dev"},
+				ForbiddenTags:     []string{"latest"},
+				RequireDigest:     true,
+			},
+			want: []string{
+				"image nginx uses registry docker.io which is not allowed",
+				"image nginx must be pinned by digest",
+				"image nginx uses forbidden tag latest",
+			},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			image, err := parseImageReference(c.image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			errs := c.policy.validate(image)
+			if len(errs) != len(c.want) {
+				t.Fatalf("got %d errors, want %d: %v", len(errs), len(c.want), errs)
+			}
+			for i, want := range c.want {
+				if errs[i].Error() != want {
+					t.Errorf("error %d: got %s, want %s", i, errs[i].Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateImagesSeverity(t *testing.T) {
+	cases := map[string]struct {
+		image     string
+		policy    *ImagePolicy
+		severity  Severity
+		hasErrors bool
+	}{
+		"NoPolicy": {
+			image: "fn",
+		},
+		"DefaultSeverity": {
+			image:     "fn",
+			policy:    &ImagePolicy{ForbiddenTags: []string{"latest"}},
+			severity:  SeverityError,
+			hasErrors: true,
+		},
+		"Warning": {
+			image:    "fn",
+			policy:   &ImagePolicy{ForbiddenTags: []string{"latest"}, Severity: SeverityWarning},
+			severity: SeverityWarning,
+		},
+		"MalformedReference": {
+			// a malformed reference is an error whatever the severity of the policy
+			image:     "Fn:v1",
+			policy:    &ImagePolicy{Severity: SeverityWarning},
+			severity:  SeverityError,
+			hasErrors: true,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+			if err := yaml.Unmarshal([]byte(fmt.Sprintf(imageConfig, c.image)), cfg); err != nil {
+				t.Fatal(err)
+			}
+			p, _ := NewParser("images", cfg)
+			result := p.ValidateImages(c.policy)
+			if c.severity == "" {
+				if len(result) != 0 {
+					t.Fatalf("unexpected results: %v", result)
+				}
+				return
+			}
+			if len(result) != 1 {
+				t.Fatalf("got %d results, want 1: %v", len(result), result)
+			}
+			if result[0].Severity != c.severity {
+				t.Errorf("got severity %s, want %s", result[0].Severity, c.severity)
+			}
+			if HasErrors(result) != c.hasErrors {
+				t.Errorf("got HasErrors %t, want %t", HasErrors(result), c.hasErrors)
+			}
+		})
+	}
+}
+
+func TestHasErrors(t *testing.T) {
+	cases := map[string]struct {
+		severities []Severity
+		want       bool
+	}{
+		"None":        {},
+		"Empty":       {severities: []Severity{""}, want: true},
+		"Error":       {severities: []Severity{SeverityInfo, SeverityError}, want: true},
+		"WarningInfo": {severities: []Severity{SeverityWarning, SeverityInfo}},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			result := []Result{}
+			for _, severity := range c.severities {
+				result = append(result, Result{Error: "finding", Severity: severity})
+			}
+			if got := HasErrors(result); got != c.want {
+				t.Errorf("got %t, want %t", got, c.want)
+			}
+		})
+	}
+}
@@ -17,18 +17,45 @@ limitations under the License.
 package ccsyntax
 
 import (
+	"fmt"
 	"sync"
 
+	"github.com/distribution/reference"
 	fnrunv1alpha1 "github.com/fnrunner/fnruntime/apis/fnrun/v1alpha1"
 	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
-	"github.com/fnrunner/fnutils/pkg/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
 )
 
+// GetImages returns the container images of the functions and services
 func (r *parser) GetImages() []*fnrunv1alpha1.Image {
+	inv, _ := r.GetImageInventory()
+	images := []*fnrunv1alpha1.Image{}
+	for _, image := range inv.Images {
+		switch image.Kind {
+		case ExecutorKindFunction:
+			images = append(images, &fnrunv1alpha1.Image{
+				Name: image.Name,
+				Kind: fnrunv1alpha1.ImageKindFunction,
+			})
+		case ExecutorKindService:
+			images = append(images, &fnrunv1alpha1.Image{
+				Name: image.Name,
+				Kind: fnrunv1alpha1.ImageKindService,
+			})
+		}
+	}
+	return images
+}
+
+// GetImageInventory returns all the executors used in the controller config
+// with the parsed image reference and the places they are used in
+func (r *parser) GetImageInventory() (*ImageInventory, []Result) {
 	img := &img{
-		images: []*fnrunv1alpha1.Image{},
+		ir:     r.ir,
+		result: []Result{},
+		images: []*ImageInfo{},
 	}
 	img.addImageFn = img.addImage
 
@@ -40,30 +67,105 @@ func (r *parser) GetImages() []*fnrunv1alpha1.Image {
 
 	// validate the external resources
 	r.walkControllerConfig(fnc)
-	return img.images
+	return &ImageInventory{Images: img.images}, DeduplicateResults(img.result)
+}
+
+type ExecutorKind string
+
+const (
+	ExecutorKindFunction ExecutorKind = "function"
+	ExecutorKindService  ExecutorKind = "service"
+	ExecutorKindWasm     ExecutorKind = "wasm"
+	ExecutorKindExec     ExecutorKind = "exec"
+)
+
+// ImageInventory contains all the executors used in a controller config
+type ImageInventory struct {
+	Images []*ImageInfo `json:"images,omitempty" yaml:"images,omitempty"`
+}
+
+// ImageInfo contains the executor, the parsed image reference and the
+// places the executor is used in. For an exec executor the name contains
+// the command and the reference is not parsed.
+type ImageInfo struct {
+	Name       string           `json:"name" yaml:"name"`
+	Kind       ExecutorKind     `json:"kind" yaml:"kind"`
+	Registry   string           `json:"registry,omitempty" yaml:"registry,omitempty"`
+	Repository string           `json:"repository,omitempty" yaml:"repository,omitempty"`
+	Tag        string           `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Digest     string           `json:"digest,omitempty" yaml:"digest,omitempty"`
+	Usages     []*OriginContext `json:"usages,omitempty" yaml:"usages,omitempty"`
+}
+
+// YAML returns the inventory in yaml format
+func (r *ImageInventory) YAML() ([]byte, error) {
+	return yaml.Marshal(r)
+}
+
+// parseImageReference parses the image name into registry, repository,
+// tag and digest. The name is normalized, e.g. a name without registry
+// resolves to docker.io
+func parseImageReference(name string) (*ImageInfo, error) {
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %q: %s", name, err.Error())
+	}
+	info := &ImageInfo{
+		Name:       name,
+		Registry:   reference.Domain(named),
+		Repository: reference.Path(named),
+	}
+	if tagged, ok := named.(reference.Tagged); ok {
+		info.Tag = tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		info.Digest = digested.Digest().String()
+	}
+	return info, nil
 }
 
 type img struct {
+	ir         *configIR
+	mr         sync.RWMutex
+	result     []Result
 	mrs        sync.RWMutex
-	images     []*fnrunv1alpha1.Image
+	images     []*ImageInfo
 	addImageFn imgAddImageFn
 }
 
-type imgAddImageFn func(*fnrunv1alpha1.Image)
+type imgAddImageFn func(oc *OriginContext, name string, kind ExecutorKind)
 
-func (r *img) addImage(img *fnrunv1alpha1.Image) {
-	//fmt.Printf("add gvk: %v \n", gvk)
+func (r *img) recordResult(result Result) {
+	r.mr.Lock()
+	defer r.mr.Unlock()
+	r.result = append(r.result, result)
+}
+
+func (r *img) addImage(oc *OriginContext, name string, kind ExecutorKind) {
 	r.mrs.Lock()
 	defer r.mrs.Unlock()
-	found := false
 	for _, image := range r.images {
-		if image.Name == img.Name {
+		if image.Name == name && image.Kind == kind {
+			image.Usages = append(image.Usages, oc.DeepCopy())
 			return
 		}
 	}
-	if !found {
-		r.images = append(r.images, img)
+	info := &ImageInfo{Name: name}
+	// exec commands and wasm module paths are not image references
+	if kind != ExecutorKindExec && !(kind == ExecutorKindWasm && isWasmPath(name)) {
+		var err error
+		info, err = parseImageReference(name)
+		if err != nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         err.Error(),
+			})
+			info = &ImageInfo{Name: name}
+		}
 	}
+	info.Kind = kind
+	info.Usages = []*OriginContext{oc.DeepCopy()}
+	r.images = append(r.images, info)
 }
 
 func (r *img) getGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *schema.GroupVersionKind {
@@ -72,23 +174,17 @@ func (r *img) getGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *schema.Gr
 }
 
 func (r *img) getgvk(oc *OriginContext, v runtime.RawExtension) *schema.GroupVersionKind {
-	gvk, _ := meta.GetGVKFromRuntimeRawExtension(v)
+	gvk, _ := r.ir.GetGVK(v)
 	return gvk
 }
 
 func (r *img) getFunctionGvk(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
-	if v.Type == ctrlcfgv1alpha1.ContainerType {
-		switch oc.FOWS {
-		case FOWService:
-			r.addImageFn(&fnrunv1alpha1.Image{
-				Name: v.Image,
-				Kind: fnrunv1alpha1.ImageKindService,
-			})
-		default:
-			r.addImageFn(&fnrunv1alpha1.Image{
-				Name: v.Image,
-				Kind: fnrunv1alpha1.ImageKindFunction,
-			})
+	for _, image := range getFunctionTypeHandler(v.Type).Images(v) {
+		kind := image.Kind
+		// functions in the services section run as a service
+		if kind == ExecutorKindFunction && oc.FOWS == FOWService {
+			kind = ExecutorKindService
 		}
+		r.addImageFn(oc, image.Name, kind)
 	}
 }
@@ -17,21 +17,23 @@ limitations under the License.
 package ccsyntax
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
 	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
-	"github.com/fnrunner/fnutils/pkg/meta"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-func (r *parser) init() (ConfigExecutionContext, GlobalVariable, []Result) {
+func (r *parser) init(ctx context.Context) (ConfigExecutionContext, GlobalVariable, []Result) {
 	i := initializer{
+		ir:   r.ir,
 		cec:  NewConfigExecutionContext(r.controllerName),
 		gvar: NewGlobalVariable(r.controllerName),
 	}
 
 	fnc := &WalkConfig{
+		ctx:             ctx,
 		gvkObjectFn:     i.initGvk,
 		functionBlockFn: i.initFunctionBlock,
 	}
@@ -43,6 +45,7 @@ func (r *parser) init() (ConfigExecutionContext, GlobalVariable, []Result) {
 }
 
 type initializer struct {
+	ir     *configIR
 	cec    ConfigExecutionContext
 	gvar   GlobalVariable
 	mr     sync.RWMutex
@@ -56,16 +59,17 @@ func (r *initializer) recordResult(result Result) {
 }
 
 func (r *initializer) initGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *schema.GroupVersionKind {
-	gvk, err := meta.GetGVKFromRuntimeRawExtension(v.Resource)
+	gvk, err := r.ir.GetGVK(v.Resource)
 	if err != nil {
 		r.recordResult(Result{
 			OriginContext: oc,
 			Error:         err.Error(),
+			Code:          CodeInvalidGVK,
 		})
 	}
 	oc.GVK = gvk
 	// initialize execution context for thr for and watch
-	if oc.FOWS == FOWFor || oc.FOWS == FOWWatch {
+	if gvk != nil && (oc.FOWS == FOWFor || oc.FOWS == FOWWatch) {
 		// initialize the gvk and rootVertex in the execution context
 		if err := r.cec.Add(oc); err != nil {
 			r.recordResult(Result{
@@ -0,0 +1,221 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// inputLessConfig is a controller config with a function of the given type
+// without an input as a variable, as a task, in a block and in a range. The
+// settings of every function and the services are supplied by the test.
+const inputLessConfig = `
+for:
+  topoDef:
+    resource:
+      apiVersion: topo.yndd.io/v1alpha1
+      kind: Definition
+    applyPipelineRef: applyPipeline
+    deletePipelineRef: deletePipeline
+%[6]s
+pipelines:
+- name: deletePipeline
+- name: applyPipeline
+  vars:
+    inputLessVar:
+      type: %[1]s
+%[2]s
+  tasks:
+    inputLessTask:
+      type: %[1]s
+%[3]s
+    inputLessBlock:
+      type: block
+      condition:
+        expression: $topoDef
+      block:
+        inputLessBlockTask:
+          type: %[1]s
+%[4]s
+    inputLessRange:
+      type: %[1]s
+      range:
+        value: $topoDef | .spec
+%[5]s
+`
+
+const inputLessServices = `
+services:
+  svc:
+    type: container
+    image: svc-image
+    output:
+      nodes:
+        resource:
+          apiVersion: topo.yndd.io/v1alpha1
+          kind: Node
+`
+
+type nopVisitor struct{}
+
+func (nopVisitor) VisitGvkObject(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) error {
+	return nil
+}
+func (nopVisitor) VisitPipeline(oc *OriginContext, v *ctrlcfgv1alpha1.Pipeline) error {
+	return nil
+}
+func (nopVisitor) VisitFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error {
+	return nil
+}
+func (nopVisitor) VisitService(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error {
+	return nil
+}
+
+func TestInputLessFunctions(t *testing.T) {
+	// the settings a function type needs besides its input, every function
+	// gets its own output
+	settings := map[ctrlcfgv1alpha1.FunctionType]string{
+		ctrlcfgv1alpha1.ContainerType:   "image: fn-image\noutput:\n  %[1]sOut:\n    resource:\n      apiVersion: topo.yndd.io/v1alpha1\n      kind: Link",
+		ctrlcfgv1alpha1.WasmType:        "image: fn-image\noutput:\n  %[1]sOut:\n    resource:\n      apiVersion: topo.yndd.io/v1alpha1\n      kind: Link",
+		ctrlcfgv1alpha1.ServiceCallType: "serviceRef:\n  name: svc\n  output: nodes\noutput:\n  %[1]sOut:\n    resource:\n      apiVersion: topo.yndd.io/v1alpha1\n      kind: Node",
+	}
+	// parseErrors are the prefixes of the results of Parse for the function
+	// types that fail to parse, the other function types parse without results
+	parseErrors := map[ctrlcfgv1alpha1.FunctionType]map[string]string{
+		// a block type needs a block and only one block is allowed in a
+		// pipeline
+		ctrlcfgv1alpha1.BlockType: {
+			"inputLessVar":       "a function block must have a block",
+			"inputLessTask":      "a function block must have a block",
+			"inputLessBlockTask": "a function block must have a block",
+		},
+	}
+	for _, fnType := range []ctrlcfgv1alpha1.FunctionType{
+		ctrlcfgv1alpha1.QueryType,
+		ctrlcfgv1alpha1.SliceType,
+		ctrlcfgv1alpha1.MapType,
+		ctrlcfgv1alpha1.JQType,
+		ctrlcfgv1alpha1.GoTemplateType,
+		ctrlcfgv1alpha1.BlockType,
+		ctrlcfgv1alpha1.ContainerType,
+		ctrlcfgv1alpha1.WasmType,
+		ctrlcfgv1alpha1.ServiceCallType,
+	} {
+		t.Run(string(fnType), func(t *testing.T) {
+			setting := func(vertexName string, n int) string {
+				if s, ok := settings[fnType]; ok {
+					return indent(fmt.Sprintf(s, vertexName), n)
+				}
+				return ""
+			}
+			services := ""
+			if fnType == ctrlcfgv1alpha1.ServiceCallType {
+				services = inputLessServices
+			}
+			cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+			if err := yaml.Unmarshal([]byte(fmt.Sprintf(inputLessConfig, fnType,
+				setting("inputLessVar", 6),
+				setting("inputLessTask", 6),
+				setting("inputLessBlockTask", 10),
+				setting("inputLessRange", 6),
+				services,
+			)), cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			p, result := NewParser("inputless", cfg)
+			c := getFunctionTypeHandler(fnType).Contract()
+			inputNeeded := fmt.Sprintf("input is needed in a function %s", fnType)
+			for _, vertexName := range []string{"inputLessVar", "inputLessTask", "inputLessBlockTask", "inputLessRange"} {
+				if got := hasResult(result, vertexName, inputNeeded); got != c.InputRequired {
+					t.Errorf("%s: input needed result %t, want %t, got: %v", vertexName, got, c.InputRequired, result)
+				}
+			}
+
+			// the parse phases report a bad config with results, Parse stops
+			// at the failed phase and ParseAll returns the partially built
+			// config execution context
+			wantErrors := parseErrors[fnType]
+			ceCtx, parseResult := p.Parse()
+			allCeCtx, allResult := p.ParseAll()
+			if allCeCtx == nil {
+				t.Error("ParseAll: expected a config execution context")
+			}
+			if len(wantErrors) == 0 {
+				if ceCtx == nil || len(parseResult) != 0 {
+					t.Errorf("Parse: unexpected results: %v", parseResult)
+				}
+				if len(allResult) != 0 {
+					t.Errorf("ParseAll: unexpected results: %v", allResult)
+				}
+			} else {
+				if ceCtx != nil {
+					t.Error("Parse: expected no config execution context")
+				}
+				for vertexName, msg := range wantErrors {
+					if !hasResultPrefix(parseResult, vertexName, msg) {
+						t.Errorf("Parse: expected %s for %s, got: %v", msg, vertexName, parseResult)
+					}
+					if !hasResultPrefix(allResult, vertexName, msg) {
+						t.Errorf("ParseAll: expected %s for %s, got: %v", msg, vertexName, allResult)
+					}
+				}
+			}
+
+			// every other phase and query should handle the function without
+			// an input, whether the config validated or not
+			p.GetImages()
+			p.GetImageInventory()
+			p.ValidateImages(&ImagePolicy{})
+			p.ValidateConfigs(NewConfigSchemaRegistry())
+			p.LintExec(&ExecPolicy{})
+			p.ValidateOwnership()
+			p.GetPolicyRules()
+			p.GetClusterRole("inputless")
+			p.GetRole("inputless", "default")
+			p.GetServiceManifests("default")
+			p.GetExternalResources()
+			p.GetExternalResourceUsages()
+			if err := p.Walk(nopVisitor{}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// hasResultPrefix is like hasResult for results that start with msg
+func hasResultPrefix(result []Result, vertexName, msg string) bool {
+	for _, r := range result {
+		if r.OriginContext != nil && r.OriginContext.VertexName == vertexName && strings.HasPrefix(r.Error, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+func indent(s string, n int) string {
+	if s == "" {
+		return ""
+	}
+	prefix := strings.Repeat(" ", n)
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"sync"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"github.com/fnrunner/fnutils/pkg/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// configIR is the intermediate representation of the controller config. It is
+// built once per parser and holds the elements of the config flattened in the
+// order they are walked, with their position in the config. The gvks and the
+// references are decoded when it is built and shared by all the phases.
+type configIR struct {
+	nodes []*irNode
+	// maxBlockDepth bounds the nesting of the block functions, zero is
+	// unbounded
+	maxBlockDepth int
+
+	m    sync.RWMutex
+	gvks map[string]*irGVK
+	refs map[string][]*Reference
+}
+
+// ConfigIR gives the function type handlers access to the gvks and the
+// references the parser decoded from the controller config
+type ConfigIR interface {
+	// GetGVK returns the gvk of a resource
+	GetGVK(v runtime.RawExtension) (*schema.GroupVersionKind, error)
+	// GetReferences returns the references in an expression
+	GetReferences(s string) []*Reference
+}
+
+type irNodeKind int
+
+const (
+	irNodeGvkObject irNodeKind = iota
+	irNodeEmptyPipeline
+	irNodePipelinePreHook
+	irNodePipelinePostHook
+	irNodeFunctionElement
+	irNodeService
+)
+
+// irNode is an element of the config at a position in the walk
+type irNode struct {
+	kind irNodeKind
+	// oc is the position of the element, the gvk is filled in during the walk
+	oc              *OriginContext
+	gvkObject       *ctrlcfgv1alpha1.GvkObject
+	pipeline        *ctrlcfgv1alpha1.Pipeline
+	functionElement *ctrlcfgv1alpha1.FunctionElement
+	function        *ctrlcfgv1alpha1.Function
+}
+
+// position returns a copy of the origin context of the node, such that the
+// walk functions can modify it
+func (r *irNode) position(gvk *schema.GroupVersionKind) *OriginContext {
+	oc := *r.oc
+	oc.GVK = gvk
+	return &oc
+}
+
+// depth returns the nesting level of the node in the config, the nodes
+// following a node at a deeper level are its children
+func (r *irNode) depth() int {
+	switch r.kind {
+	case irNodeGvkObject, irNodeService:
+		return 0
+	case irNodeFunctionElement:
+		return 2 + r.oc.BlockIndex
+	default:
+		return 1
+	}
+}
+
+type irGVK struct {
+	gvk *schema.GroupVersionKind
+	err error
+}
+
+func newConfigIR(cfg *ctrlcfgv1alpha1.ControllerConfigSpec) *configIR {
+	return newLimitedConfigIR(cfg, Limits{})
+}
+
+// newLimitedConfigIR builds the intermediate representation without the
+// block functions nested deeper than the MaxBlockDepth of the limits
+func newLimitedConfigIR(cfg *ctrlcfgv1alpha1.ControllerConfigSpec, limits Limits) *configIR {
+	r := &configIR{
+		nodes:         []*irNode{},
+		maxBlockDepth: limits.MaxBlockDepth,
+		gvks:          map[string]*irGVK{},
+		refs:          map[string][]*Reference{},
+	}
+	fors := cfg.GetFors()
+	for _, vertexName := range sortedKeys(fors) {
+		r.addGvkObject(cfg, &OriginContext{FOWS: FOWFor, RootVertexName: vertexName, Origin: OriginFow, VertexName: vertexName}, fors[vertexName])
+	}
+	owns := cfg.GetOwns()
+	for _, vertexName := range sortedKeys(owns) {
+		r.addGvkObject(cfg, &OriginContext{FOWS: FOWOwn, RootVertexName: vertexName, Origin: OriginFow, VertexName: vertexName}, owns[vertexName])
+	}
+	watches := cfg.GetWatches()
+	for _, vertexName := range sortedKeys(watches) {
+		r.addGvkObject(cfg, &OriginContext{FOWS: FOWWatch, RootVertexName: vertexName, Origin: OriginFow, VertexName: vertexName}, watches[vertexName])
+	}
+	services := cfg.GetServices()
+	for _, vertexName := range cfg.GetServiceNames() {
+		r.nodes = append(r.nodes, &irNode{
+			kind:     irNodeService,
+			oc:       &OriginContext{FOWS: FOWService, RootVertexName: vertexName, Origin: OriginService, VertexName: vertexName},
+			function: services[vertexName],
+		})
+	}
+	r.decode()
+	return r
+}
+
+// addGvkObject adds the for, own or watch with its apply and delete pipeline
+func (r *configIR) addGvkObject(cfg *ctrlcfgv1alpha1.ControllerConfigSpec, oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) {
+	if v == nil {
+		// an empty entry is validated as a gvk object without a resource
+		v = &ctrlcfgv1alpha1.GvkObject{}
+	}
+	r.nodes = append(r.nodes, &irNode{kind: irNodeGvkObject, oc: oc, gvkObject: v})
+	for _, p := range []struct {
+		op  Operation
+		ref string
+	}{
+		{op: OperationApply, ref: v.ApplyPipelineRef},
+		{op: OperationDelete, ref: v.DeletePipelineRef},
+	} {
+		oc := &OriginContext{FOWS: oc.FOWS, RootVertexName: oc.RootVertexName, Operation: p.op, Origin: oc.Origin, VertexName: oc.VertexName}
+		pipeline := cfg.GetPipeline(p.ref)
+		if pipeline == nil {
+			r.nodes = append(r.nodes, &irNode{kind: irNodeEmptyPipeline, oc: oc, gvkObject: v})
+			continue
+		}
+		r.addPipeline(oc, pipeline)
+	}
+}
+
+func (r *configIR) addPipeline(oc *OriginContext, v *ctrlcfgv1alpha1.Pipeline) {
+	hookOc := &OriginContext{FOWS: oc.FOWS, RootVertexName: oc.RootVertexName, Operation: oc.Operation, Pipeline: v.Name, Origin: oc.Origin, VertexName: oc.VertexName}
+	r.nodes = append(r.nodes, &irNode{kind: irNodePipelinePreHook, oc: hookOc, pipeline: v})
+	for _, vertexName := range sortedKeys(v.Vars) {
+		r.addFunctionElement(&OriginContext{FOWS: oc.FOWS, RootVertexName: oc.RootVertexName, Operation: oc.Operation, Pipeline: v.Name, Origin: OriginVariable, VertexName: vertexName}, v.Vars[vertexName])
+	}
+	for _, vertexName := range sortedKeys(v.Tasks) {
+		r.addFunctionElement(&OriginContext{FOWS: oc.FOWS, RootVertexName: oc.RootVertexName, Operation: oc.Operation, Pipeline: v.Name, Origin: OriginFunction, VertexName: vertexName}, v.Tasks[vertexName])
+	}
+	r.nodes = append(r.nodes, &irNode{kind: irNodePipelinePostHook, oc: hookOc, pipeline: v})
+}
+
+// addFunctionElement adds the function and the functions in its block
+func (r *configIR) addFunctionElement(oc *OriginContext, v *ctrlcfgv1alpha1.FunctionElement) {
+	if v != nil {
+		oc.LocalVars = v.Vars
+	}
+	r.nodes = append(r.nodes, &irNode{kind: irNodeFunctionElement, oc: oc, functionElement: v})
+	if v == nil || v.Type != ctrlcfgv1alpha1.BlockType {
+		return
+	}
+	if r.maxBlockDepth != 0 && oc.BlockIndex >= r.maxBlockDepth {
+		return
+	}
+	for _, vertexName := range sortedKeys(v.FunctionBlock) {
+		r.addFunctionElement(&OriginContext{
+			FOWS:            oc.FOWS,
+			RootVertexName:  oc.RootVertexName,
+			Operation:       oc.Operation,
+			Pipeline:        oc.Pipeline,
+			Origin:          oc.Origin,
+			Block:           true,
+			BlockIndex:      oc.BlockIndex + 1,
+			BlockVertexName: oc.VertexName,
+			VertexName:      vertexName,
+			LocalVars:       oc.LocalVars,
+		}, v.FunctionBlock[vertexName])
+	}
+}
+
+// decode decodes the gvks and the references of the config when the
+// intermediate representation is built, such that the phases look them up
+func (r *configIR) decode() {
+	for _, n := range r.nodes {
+		switch n.kind {
+		case irNodeGvkObject:
+			r.decodeGVK(n.gvkObject.Resource)
+		case irNodeFunctionElement:
+			if n.functionElement != nil {
+				r.decodeFunction(&n.functionElement.Function)
+			}
+		case irNodeService:
+			if n.function != nil {
+				r.decodeFunction(n.function)
+			}
+		}
+	}
+}
+
+func (r *configIR) decodeFunction(v *ctrlcfgv1alpha1.Function) {
+	if v.Input != nil {
+		r.decodeGVK(v.Input.Resource)
+	}
+	for _, outputCfg := range v.Output {
+		if outputCfg != nil {
+			r.decodeGVK(outputCfg.Resource)
+		}
+	}
+	for _, s := range getFunctionExpressions(v) {
+		r.decodeReferences(s)
+	}
+}
+
+func (r *configIR) decodeGVK(v runtime.RawExtension) *irGVK {
+	gvk, err := meta.GetGVKFromRuntimeRawExtension(v)
+	e := &irGVK{gvk: gvk, err: err}
+	r.m.Lock()
+	r.gvks[string(v.Raw)] = e
+	r.m.Unlock()
+	return e
+}
+
+func (r *configIR) decodeReferences(s string) []*Reference {
+	refs := NewReferences().GetReferences(s)
+	r.m.Lock()
+	r.refs[s] = refs
+	r.m.Unlock()
+	return refs
+}
+
+// GetGVK returns the gvk of the resource, the resources of the config are
+// decoded when the intermediate representation is built
+func (r *configIR) GetGVK(v runtime.RawExtension) (*schema.GroupVersionKind, error) {
+	r.m.RLock()
+	e, ok := r.gvks[string(v.Raw)]
+	r.m.RUnlock()
+	if !ok {
+		// a resource from outside the config
+		e = r.decodeGVK(v)
+	}
+	if e.gvk == nil {
+		return nil, e.err
+	}
+	// the caller gets its own copy of the gvk
+	gvk := *e.gvk
+	return &gvk, e.err
+}
+
+// GetReferences returns the references in the string, the expressions of the
+// config are tokenized when the intermediate representation is built. The
+// references are shared and should not be modified.
+func (r *configIR) GetReferences(s string) []*Reference {
+	r.m.RLock()
+	refs, ok := r.refs[s]
+	r.m.RUnlock()
+	if !ok {
+		// an expression from outside the config
+		refs = r.decodeReferences(s)
+	}
+	return refs
+}
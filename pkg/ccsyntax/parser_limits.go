@@ -0,0 +1,300 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+
+	"github.com/fnrunner/fnruntime/pkg/exec/rtdag"
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"github.com/fnrunner/fnutils/pkg/meta"
+)
+
+// Limits caps the size of the controller config, such that configs of
+// untrusted tenants cannot exhaust the parser. A zero value disables the
+// limit.
+type Limits struct {
+	// MaxVertices is the maximum number of vertices in the dag of a pipeline
+	MaxVertices int `json:"maxVertices,omitempty" yaml:"maxVertices,omitempty"`
+	// MaxBlockDepth is the maximum nesting depth of the block functions and
+	// the range and condition blocks of a function
+	MaxBlockDepth int `json:"maxBlockDepth,omitempty" yaml:"maxBlockDepth,omitempty"`
+	// MaxReferences is the maximum number of references in a function
+	MaxReferences int `json:"maxReferences,omitempty" yaml:"maxReferences,omitempty"`
+	// MaxExpressionSize is the maximum size in bytes of an expression that
+	// can hold references
+	MaxExpressionSize int `json:"maxExpressionSize,omitempty" yaml:"maxExpressionSize,omitempty"`
+	// MaxPaths is the maximum number of paths the transitive reduction walks
+	// in the dags of a pipeline. The reduction walks every path from every
+	// vertex, so its cost grows exponentially with the references between
+	// the vertices even when the other limits are met. The paths are counted
+	// before the reduction removes any edge, which bounds the cost from
+	// above. It is checked when the config is parsed.
+	MaxPaths int `json:"maxPaths,omitempty" yaml:"maxPaths,omitempty"`
+}
+
+// checkLimits checks the controller config against the limits before the
+// intermediate representation is built, such that a config that exceeds the
+// limits is not decoded. The pipelines are walked like in the intermediate
+// representation.
+func checkLimits(cfg *ctrlcfgv1alpha1.ControllerConfigSpec, limits Limits) []Result {
+	if limits == (Limits{}) {
+		return nil
+	}
+	lc := &limitChecker{
+		cfg:    cfg,
+		limits: limits,
+		result: []Result{},
+	}
+	fors := cfg.GetFors()
+	for _, vertexName := range sortedKeys(fors) {
+		lc.checkGvkObject(&OriginContext{FOWS: FOWFor, RootVertexName: vertexName, Origin: OriginFow, VertexName: vertexName}, fors[vertexName])
+	}
+	owns := cfg.GetOwns()
+	for _, vertexName := range sortedKeys(owns) {
+		lc.checkGvkObject(&OriginContext{FOWS: FOWOwn, RootVertexName: vertexName, Origin: OriginFow, VertexName: vertexName}, owns[vertexName])
+	}
+	watches := cfg.GetWatches()
+	for _, vertexName := range sortedKeys(watches) {
+		lc.checkGvkObject(&OriginContext{FOWS: FOWWatch, RootVertexName: vertexName, Origin: OriginFow, VertexName: vertexName}, watches[vertexName])
+	}
+	services := cfg.GetServices()
+	for _, vertexName := range cfg.GetServiceNames() {
+		if services[vertexName] != nil {
+			lc.checkFunction(&OriginContext{FOWS: FOWService, RootVertexName: vertexName, Origin: OriginService, VertexName: vertexName}, services[vertexName])
+		}
+	}
+	return lc.result
+}
+
+type limitChecker struct {
+	cfg    *ctrlcfgv1alpha1.ControllerConfigSpec
+	limits Limits
+	result []Result
+	// vertices counts the vertices of the pipeline that is checked
+	vertices int
+}
+
+func (r *limitChecker) recordResult(result Result) {
+	r.result = append(r.result, result)
+}
+
+func (r *limitChecker) checkGvkObject(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) {
+	if v == nil {
+		return
+	}
+	// an invalid gvk is reported by the validator
+	oc.GVK, _ = meta.GetGVKFromRuntimeRawExtension(v.Resource)
+	for _, p := range []struct {
+		op  Operation
+		ref string
+	}{
+		{op: OperationApply, ref: v.ApplyPipelineRef},
+		{op: OperationDelete, ref: v.DeletePipelineRef},
+	} {
+		if pipeline := r.cfg.GetPipeline(p.ref); pipeline != nil {
+			r.checkPipeline(&OriginContext{FOWS: oc.FOWS, RootVertexName: oc.RootVertexName, GVK: oc.GVK, Operation: p.op, Pipeline: pipeline.Name, Origin: oc.Origin, VertexName: oc.VertexName}, pipeline)
+		}
+	}
+}
+
+func (r *limitChecker) checkPipeline(oc *OriginContext, v *ctrlcfgv1alpha1.Pipeline) {
+	r.vertices = 0
+	for _, vertexName := range sortedKeys(v.Vars) {
+		r.checkFunctionElement(&OriginContext{FOWS: oc.FOWS, RootVertexName: oc.RootVertexName, GVK: oc.GVK, Operation: oc.Operation, Pipeline: v.Name, Origin: OriginVariable, VertexName: vertexName}, v.Vars[vertexName])
+	}
+	for _, vertexName := range sortedKeys(v.Tasks) {
+		r.checkFunctionElement(&OriginContext{FOWS: oc.FOWS, RootVertexName: oc.RootVertexName, GVK: oc.GVK, Operation: oc.Operation, Pipeline: v.Name, Origin: OriginFunction, VertexName: vertexName}, v.Tasks[vertexName])
+	}
+	if r.limits.MaxVertices != 0 && r.vertices > r.limits.MaxVertices {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("pipeline %s has %d vertices, exceeds the limit of %d", v.Name, r.vertices, r.limits.MaxVertices).Error(),
+			Code:          CodeLimitExceeded,
+		})
+	}
+}
+
+// checkFunctionElement checks the function and the functions in its block.
+// The walk stops at the first level of block functions that exceeds the
+// MaxBlockDepth, the functions nested in them are not checked.
+func (r *limitChecker) checkFunctionElement(oc *OriginContext, v *ctrlcfgv1alpha1.FunctionElement) {
+	if v == nil {
+		return
+	}
+	r.vertices++
+	if v.Type == ctrlcfgv1alpha1.BlockType {
+		oc.Block = true
+	}
+	r.checkFunction(oc, &v.Function)
+	if v.Type != ctrlcfgv1alpha1.BlockType || (r.limits.MaxBlockDepth != 0 && oc.BlockIndex > r.limits.MaxBlockDepth) {
+		return
+	}
+	for _, vertexName := range sortedKeys(v.FunctionBlock) {
+		r.checkFunctionElement(&OriginContext{
+			FOWS:            oc.FOWS,
+			RootVertexName:  oc.RootVertexName,
+			GVK:             oc.GVK,
+			Operation:       oc.Operation,
+			Pipeline:        oc.Pipeline,
+			Origin:          oc.Origin,
+			Block:           true,
+			BlockIndex:      oc.BlockIndex + 1,
+			BlockVertexName: oc.VertexName,
+			VertexName:      vertexName,
+		}, v.FunctionBlock[vertexName])
+	}
+}
+
+func (r *limitChecker) checkFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
+	if r.limits.MaxBlockDepth != 0 {
+		// the functions in a block are nested one level deeper than the
+		// function of the block
+		if depth := oc.BlockIndex + blockDepth(v.Block); depth > r.limits.MaxBlockDepth {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("block depth %d exceeds the limit of %d", depth, r.limits.MaxBlockDepth).Error(),
+				Code:          CodeLimitExceeded,
+			})
+		}
+	}
+	references := 0
+	for _, s := range getFunctionExpressions(v) {
+		if r.limits.MaxExpressionSize != 0 && len(s) > r.limits.MaxExpressionSize {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("expression of %d bytes exceeds the limit of %d", len(s), r.limits.MaxExpressionSize).Error(),
+				Code:          CodeLimitExceeded,
+			})
+			// an oversized expression is not scanned for references
+			continue
+		}
+		if r.limits.MaxReferences != 0 {
+			references += len(NewReferences().GetReferences(s))
+		}
+	}
+	if r.limits.MaxReferences != 0 && references > r.limits.MaxReferences {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("function has %d references, exceeds the limit of %d", references, r.limits.MaxReferences).Error(),
+			Code:          CodeLimitExceeded,
+		})
+	}
+}
+
+// getFunctionExpressions returns the expressions of the function that can
+// hold references
+func getFunctionExpressions(v *ctrlcfgv1alpha1.Function) []string {
+	exprs := []string{}
+	for _, localVarName := range sortedKeys(v.Vars) {
+		exprs = append(exprs, v.Vars[localVarName])
+	}
+	exprs = append(exprs, getBlockExpressions(v.Block)...)
+	return append(exprs, getFunctionTypeHandler(v.Type).References(v)...)
+}
+
+func getBlockExpressions(v ctrlcfgv1alpha1.Block) []string {
+	exprs := []string{}
+	if v.Range != nil {
+		exprs = append(exprs, v.Range.Value)
+		exprs = append(exprs, getBlockExpressions(v.Range.Block)...)
+	}
+	if v.Condition != nil {
+		exprs = append(exprs, v.Condition.Expression)
+		exprs = append(exprs, getBlockExpressions(v.Condition.Block)...)
+	}
+	return exprs
+}
+
+// blockDepth returns the nesting depth of the range and condition blocks
+func blockDepth(v ctrlcfgv1alpha1.Block) int {
+	depth := 0
+	if v.Range != nil {
+		if d := 1 + blockDepth(v.Range.Block); d > depth {
+			depth = d
+		}
+	}
+	if v.Condition != nil {
+		if d := 1 + blockDepth(v.Condition.Block); d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// checkPaths checks the number of paths in the dags of the config execution
+// context against the MaxPaths, before the transitive reduction walks them
+func (r *parser) checkPaths(ceCtx ConfigExecutionContext) []Result {
+	if r.limits.MaxPaths == 0 {
+		return nil
+	}
+	result := []Result{}
+	for _, fow := range []FOWS{FOWFor, FOWWatch} {
+		fowDAGs := ceCtx.GetFOW(fow)
+		for _, gvk := range sortedGVKs(fowDAGs) {
+			gvk := gvk
+			for _, op := range sortedKeys(fowDAGs[gvk]) {
+				dctx := fowDAGs[gvk][op]
+				paths := countPaths(dctx.DAG, r.limits.MaxPaths)
+				for _, d := range dctx.BlockDAGs {
+					paths += countPaths(d, r.limits.MaxPaths)
+				}
+				if paths > r.limits.MaxPaths {
+					result = append(result, Result{
+						OriginContext: &OriginContext{FOWS: fow, RootVertexName: dctx.RootVertexName, GVK: &gvk, Operation: op},
+						Error:         fmt.Errorf("dag has more paths than the limit of %d", r.limits.MaxPaths).Error(),
+						Code:          CodeLimitExceeded,
+					})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// countPaths returns the number of paths from all the vertices of the dag,
+// counting stops above max. A cycle counts as more than max paths.
+func countPaths(d rtdag.RuntimeDAG, max int) int {
+	// paths is zero for the vertices on the walk
+	paths := map[string]int{}
+	var count func(vertexName string) int
+	count = func(vertexName string) int {
+		if n, ok := paths[vertexName]; ok {
+			if n == 0 {
+				return max + 1
+			}
+			return n
+		}
+		paths[vertexName] = 0
+		n := 1
+		for _, downVertexName := range d.GetDownVertexes(vertexName) {
+			if n += count(downVertexName); n > max {
+				n = max + 1
+				break
+			}
+		}
+		paths[vertexName] = n
+		return n
+	}
+	total := 0
+	for _, vertexName := range sortedKeys(d.GetVertices()) {
+		if total += count(vertexName); total > max {
+			return max + 1
+		}
+	}
+	return total
+}
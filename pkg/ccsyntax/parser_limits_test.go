@@ -0,0 +1,252 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fnrunner/fnruntime/pkg/exec/rtdag"
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// the apply pipeline has 3 vertices, the task has a block depth of 2 and 5
+// references of which the longest expression is 21 bytes
+const limitsConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+pipelines:
+- name: apply
+  vars:
+    name:
+      type: jq
+      input:
+        expression: $pod.metadata.name
+    labels:
+      type: jq
+      input:
+        expression: $pod.metadata.labels
+  tasks:
+    nested:
+      type: jq
+      range:
+        value: $labels
+        condition:
+          expression: $name != ""
+      input:
+        expression: $name + $KEY + $VALUE
+      output:
+        nested:
+          resource:
+            apiVersion: v1
+            kind: ConfigMap
+- name: delete
+`
+
+func TestLimits(t *testing.T) {
+	cases := map[string]struct {
+		limits     Limits
+		vertexName string
+		want       string
+	}{
+		"None": {},
+		"WithinLimits": {
+			limits: Limits{MaxVertices: 3, MaxBlockDepth: 2, MaxReferences: 5, MaxExpressionSize: 21},
+		},
+		"MaxVertices": {
+			limits:     Limits{MaxVertices: 2},
+			vertexName: "pod",
+			want:       "pipeline apply has 3 vertices, exceeds the limit of 2",
+		},
+		"MaxBlockDepth": {
+			limits:     Limits{MaxBlockDepth: 1},
+			vertexName: "nested",
+			want:       "block depth 2 exceeds the limit of 1",
+		},
+		"MaxReferences": {
+			limits:     Limits{MaxReferences: 4},
+			vertexName: "nested",
+			want:       "function has 5 references, exceeds the limit of 4",
+		},
+		"MaxExpressionSize": {
+			limits:     Limits{MaxExpressionSize: 20},
+			vertexName: "nested",
+			want:       "expression of 21 bytes exceeds the limit of 20",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+			if err := yaml.Unmarshal([]byte(limitsConfig), cfg); err != nil {
+				t.Fatal(err)
+			}
+			p, result := NewParser("test", cfg, WithLimits(c.limits))
+			if c.want == "" {
+				if len(result) != 0 {
+					t.Fatalf("unexpected results: %v", result)
+				}
+				if ceCtx, result := p.Parse(); ceCtx == nil || len(result) != 0 {
+					t.Fatalf("unexpected parse results: %v", result)
+				}
+				return
+			}
+			if len(result) != 1 || result[0].Code != CodeLimitExceeded || !hasResult(result, c.vertexName, c.want) {
+				t.Fatalf("got %v, want %s for %s", result, c.want, c.vertexName)
+			}
+			// the config is not parsed and parse returns the same results
+			ceCtx, parseResult := p.Parse()
+			if ceCtx != nil {
+				t.Error("expected no config execution context")
+			}
+			if len(parseResult) != 1 || parseResult[0].Error != c.want {
+				t.Errorf("got parse results %v, want %s", parseResult, c.want)
+			}
+		})
+	}
+}
+
+// nestedBlockConfig returns a config with a chain of depth block functions
+// in the apply pipeline, the function at depth i is named block-i
+func nestedBlockConfig(t *testing.T, depth int) *ctrlcfgv1alpha1.ControllerConfigSpec {
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal([]byte(limitsConfig), cfg); err != nil {
+		t.Fatal(err)
+	}
+	var fe *ctrlcfgv1alpha1.FunctionElement
+	for i := depth; i >= 0; i-- {
+		next := &ctrlcfgv1alpha1.FunctionElement{
+			Function: ctrlcfgv1alpha1.Function{Type: ctrlcfgv1alpha1.BlockType},
+		}
+		if fe != nil {
+			next.FunctionBlock = map[string]*ctrlcfgv1alpha1.FunctionElement{fmt.Sprintf("block-%d", i+1): fe}
+		}
+		fe = next
+	}
+	cfg.GetPipeline("apply").Tasks["block-0"] = fe
+	return cfg
+}
+
+func TestNestedBlockLimits(t *testing.T) {
+	// the walk stops below the first block function that exceeds the limit
+	cfg := nestedBlockConfig(t, 1000)
+	p, result := NewParser("test", cfg, WithLimits(Limits{MaxBlockDepth: 2}))
+	if len(result) != 1 || !hasResult(result, "block-3", "block depth 3 exceeds the limit of 2") {
+		t.Fatalf("got %v, want block depth 3 exceeds the limit of 2 for block-3", result)
+	}
+	// the config that exceeds the limits is not decoded
+	if nodes := p.(*parser).ir.nodes; len(nodes) != 0 {
+		t.Errorf("got %d nodes in the intermediate representation, want none", len(nodes))
+	}
+}
+
+func TestLimitedConfigIR(t *testing.T) {
+	cfg := nestedBlockConfig(t, 10)
+	for _, c := range []struct {
+		limits Limits
+		want   int
+	}{
+		{want: 11},
+		{limits: Limits{MaxBlockDepth: 2}, want: 3},
+	} {
+		blocks := 0
+		for _, n := range newLimitedConfigIR(cfg, c.limits).nodes {
+			if n.kind == irNodeFunctionElement && strings.HasPrefix(n.oc.VertexName, "block-") {
+				blocks++
+			}
+		}
+		if blocks != c.want {
+			t.Errorf("got %d block functions with max block depth %d, want %d", blocks, c.limits.MaxBlockDepth, c.want)
+		}
+	}
+}
+
+func TestCountPaths(t *testing.T) {
+	newDAG := func(edges ...[2]string) rtdag.RuntimeDAG {
+		d := rtdag.New()
+		for _, e := range edges {
+			for _, vertexName := range e {
+				if !d.VertexExists(vertexName) {
+					if err := d.AddVertex(vertexName, nil); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			d.Connect(e[0], e[1])
+		}
+		return d
+	}
+	// the diamond has 5 paths from a, 2 from b and c and 1 from d
+	diamond := newDAG([2]string{"a", "b"}, [2]string{"a", "c"}, [2]string{"b", "d"}, [2]string{"c", "d"})
+	cases := map[string]struct {
+		d    rtdag.RuntimeDAG
+		max  int
+		want int
+	}{
+		"Diamond":      {d: diamond, max: 100, want: 10},
+		"DiamondAtMax": {d: diamond, max: 10, want: 10},
+		"OverMax":      {d: diamond, max: 7, want: 8},
+		"Cycle":        {d: newDAG([2]string{"a", "b"}, [2]string{"b", "a"}), max: 100, want: 101},
+		"SelfLoop":     {d: newDAG([2]string{"a", "a"}), max: 100, want: 101},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := countPaths(c.d, c.max); got != c.want {
+				t.Errorf("got %d paths, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestMaxPaths(t *testing.T) {
+	for _, c := range []struct {
+		maxPaths int
+		want     string
+	}{
+		{maxPaths: 100},
+		{maxPaths: 2, want: "dag has more paths than the limit of 2"},
+	} {
+		cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+		if err := yaml.Unmarshal([]byte(limitsConfig), cfg); err != nil {
+			t.Fatal(err)
+		}
+		// the paths are only known when the config is parsed
+		p, result := NewParser("test", cfg, WithLimits(Limits{MaxPaths: c.maxPaths}))
+		if len(result) != 0 {
+			t.Fatalf("unexpected results: %v", result)
+		}
+		ceCtx, result := p.Parse()
+		if c.want == "" {
+			if ceCtx == nil || len(result) != 0 {
+				t.Errorf("unexpected parse results with max paths %d: %v", c.maxPaths, result)
+			}
+			continue
+		}
+		if ceCtx != nil {
+			t.Error("expected no config execution context")
+		}
+		if len(result) != 1 || result[0].Code != CodeLimitExceeded || result[0].Error != c.want || result[0].OriginContext.RootVertexName != "pod" {
+			t.Errorf("got %v, want %s for pod", result, c.want)
+		}
+	}
+}
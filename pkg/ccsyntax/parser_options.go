@@ -0,0 +1,46 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"github.com/go-logr/logr"
+)
+
+// Option configures the parser
+type Option func(*parser)
+
+// WithLogger sets the logger of the parser
+func WithLogger(l logr.Logger) Option {
+	return func(r *parser) {
+		r.l = l
+	}
+}
+
+// WithLimits sets the limits the controller config has to stay within
+func WithLimits(limits Limits) Option {
+	return func(r *parser) {
+		r.limits = limits
+	}
+}
+
+// WithKeepGoing makes Parse and ParseContext continue with the next phases
+// when a phase fails, like ParseAll
+func WithKeepGoing() Option {
+	return func(r *parser) {
+		r.keepGoing = true
+	}
+}
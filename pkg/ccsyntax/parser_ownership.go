@@ -0,0 +1,158 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"sync"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"github.com/fnrunner/fnutils/pkg/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ValidateOwnership cross checks the resources the functions create in the
+// cluster with the own section of the controller config. The own section
+// drives the garbage collection and the owner reference watches, so
+// every external output must be owned, every own entry must be produced by a
+// function and a gvk cannot be used in both the for and own section.
+func (r *parser) ValidateOwnership() []Result {
+	ov := &ov{
+		ir:      r.ir,
+		result:  []Result{},
+		fors:    map[schema.GroupVersionKind]*OriginContext{},
+		owns:    map[schema.GroupVersionKind]*OriginContext{},
+		outputs: map[schema.GroupVersionKind]*OriginContext{},
+	}
+
+	fnc := &WalkConfig{
+		gvkObjectFn: ov.getGvk,
+		functionFn:  ov.getFunctionGvk,
+	}
+
+	// walk the config to collect the for, own and external output gvks
+	r.walkControllerConfig(fnc)
+	ov.validate()
+	return DeduplicateResults(ov.result)
+}
+
+type ov struct {
+	ir     *configIR
+	mr     sync.RWMutex
+	result []Result
+	mg     sync.RWMutex
+	// the key is the gvk, the value the first origin in which the gvk was found
+	fors    map[schema.GroupVersionKind]*OriginContext
+	owns    map[schema.GroupVersionKind]*OriginContext
+	outputs map[schema.GroupVersionKind]*OriginContext
+}
+
+func (r *ov) recordResult(result Result) {
+	r.mr.Lock()
+	defer r.mr.Unlock()
+	r.result = append(r.result, result)
+}
+
+func (r *ov) addGvk(m map[schema.GroupVersionKind]*OriginContext, oc *OriginContext, gvk *schema.GroupVersionKind) {
+	if gvk == nil {
+		return
+	}
+	r.mg.Lock()
+	defer r.mg.Unlock()
+	if _, ok := m[*gvk]; !ok {
+		m[*gvk] = oc.DeepCopy()
+	}
+}
+
+func (r *ov) getGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *schema.GroupVersionKind {
+	gvk := r.getgvk(oc, v.Resource)
+	switch oc.FOWS {
+	case FOWFor:
+		r.addGvk(r.fors, oc, gvk)
+	case FOWOwn:
+		r.addGvk(r.owns, oc, gvk)
+	}
+	return gvk
+}
+
+func (r *ov) getFunctionGvk(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
+	// a gotemplate without output creates the resource in the input
+	fos, errs := getFunctionTypeHandler(v.Type).Outputs(r.ir, oc.VertexName, v)
+	for _, err := range errs {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+		})
+	}
+	for _, fo := range fos {
+		if !fo.Internal {
+			r.addGvk(r.outputs, oc, fo.GVK)
+		}
+	}
+}
+
+func (r *ov) getgvk(oc *OriginContext, v runtime.RawExtension) *schema.GroupVersionKind {
+	gvk, err := r.ir.GetGVK(v)
+	if err != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+			Code:          CodeInvalidGVK,
+		})
+	}
+	return gvk
+}
+
+func (r *ov) validate() {
+	r.mg.RLock()
+	defer r.mg.RUnlock()
+	for _, gvk := range sortedGVKs(r.fors) {
+		gvk := gvk
+		oc := r.fors[gvk]
+		if _, ok := r.owns[gvk]; ok {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("gvk %s cannot be used in both for and own", meta.GVKToString(&gvk)).Error(),
+			})
+		}
+	}
+	for _, gvk := range sortedGVKs(r.outputs) {
+		gvk := gvk
+		oc := r.outputs[gvk]
+		// the for resource is reconciled by the controller itself
+		if _, ok := r.fors[gvk]; ok {
+			continue
+		}
+		if _, ok := r.owns[gvk]; !ok {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("resource %s is created by a function but not owned", meta.GVKToString(&gvk)).Error(),
+			})
+		}
+	}
+	for _, gvk := range sortedGVKs(r.owns) {
+		gvk := gvk
+		oc := r.owns[gvk]
+		if _, ok := r.outputs[gvk]; !ok {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("own resource %s is not produced by any function", meta.GVKToString(&gvk)).Error(),
+			})
+		}
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// ownershipConfig creates a config map in the apply pipeline of the pod, the
+// own section and the internal flag of the output are supplied by the test
+const ownershipConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+%[1]s
+pipelines:
+- name: apply
+  tasks:
+    cm:
+      type: jq
+      input:
+        expression: $pod.metadata.name
+      output:
+        cm:
+          internal: %[2]t
+          resource:
+            apiVersion: v1
+            kind: ConfigMap
+- name: delete
+`
+
+const (
+	ownConfigMap = `
+  cm:
+    resource:
+      apiVersion: v1
+      kind: ConfigMap`
+	ownSecret = `
+  secret:
+    resource:
+      apiVersion: v1
+      kind: Secret`
+	ownPod = `
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod`
+)
+
+func TestValidateOwnership(t *testing.T) {
+	cases := map[string]struct {
+		own      string
+		internal bool
+		want     []string
+	}{
+		"Owned": {
+			own: ownConfigMap,
+		},
+		"NotOwned": {
+			want: []string{"resource ConfigMap.v1 is created by a function but not owned"},
+		},
+		"Internal": {
+			internal: true,
+		},
+		"NotProduced": {
+			own:  ownConfigMap + ownSecret,
+			want: []string{"own resource Secret.v1 is not produced by any function"},
+		},
+		"ForAndOwn": {
+			own: ownConfigMap + ownPod,
+			want: []string{
+				"gvk Pod.v1 cannot be used in both for and own",
+				// the pod is not produced by a function either
+				"own resource Pod.v1 is not produced by any function",
+			},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			own := ""
+			if c.own != "" {
+				own = "own:" + c.own
+			}
+			cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+			if err := yaml.Unmarshal([]byte(fmt.Sprintf(ownershipConfig, own, c.internal)), cfg); err != nil {
+				t.Fatal(err)
+			}
+			p, result := NewParser("ownership", cfg)
+			if len(result) != 0 {
+				t.Fatalf("unexpected validation results: %v", result)
+			}
+			result = p.ValidateOwnership()
+			if len(result) != len(c.want) {
+				t.Fatalf("got %d results, want %d: %v", len(result), len(c.want), result)
+			}
+			for i, want := range c.want {
+				if !strings.HasPrefix(result[i].Error, want) {
+					t.Errorf("result %d: got %s, want %s", i, result[i].Error, want)
+				}
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package ccsyntax
 
 import (
+	"context"
+	"fmt"
 	"sync"
 
 	"github.com/fnrunner/fnruntime/pkg/exec/output"
@@ -27,17 +29,23 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-func (r *parser) populate(cec ConfigExecutionContext, gvar GlobalVariable) []Result {
+func (r *parser) populate(ctx context.Context, cec ConfigExecutionContext, gvar GlobalVariable, failed *failedVertices) []Result {
 	p := &populator{
-		cec:    cec,
-		gvar:   gvar,
-		result: []Result{},
+		ir:       r.ir,
+		cec:      cec,
+		gvar:     gvar,
+		failed:   failed,
+		result:   []Result{},
+		usedGvks: map[schema.GroupVersionKind]struct{}{},
 	}
 
 	fnc := &WalkConfig{
-		gvkObjectFn: p.addGvk,
-		functionFn:  p.addFunction,
-		//serviceFn:   p.addService,
+		ctx:           ctx,
+		cfgPreHookFn:  p.addServices,
+		gvkObjectFn:   p.addGvk,
+		functionFn:    p.addFunction,
+		serviceFn:     p.addService,
+		cfgPostHookFn: p.validateServices,
 	}
 
 	// walk the config populate the verteces and create the hierarchical DAG
@@ -48,10 +56,16 @@ func (r *parser) populate(cec ConfigExecutionContext, gvar GlobalVariable) []Res
 }
 
 type populator struct {
+	ir     *configIR
 	cec    ConfigExecutionContext
 	gvar   GlobalVariable
+	failed *failedVertices
 	mr     sync.RWMutex
 	result []Result
+	// usedGvks records the gvks the functions consume or produce, used to
+	// validate the services
+	mu       sync.RWMutex
+	usedGvks map[schema.GroupVersionKind]struct{}
 }
 
 func (r *populator) recordResult(result Result) {
@@ -62,11 +76,16 @@ func (r *populator) recordResult(result Result) {
 
 func (r *populator) addGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *schema.GroupVersionKind {
 	// a gvk is needed for each rootVertex
-	gvk, err := meta.GetGVKFromRuntimeRawExtension(v.Resource)
+	gvk, err := r.ir.GetGVK(v.Resource)
+	if r.failed.has(oc) {
+		// the root vertex failed in a previous phase
+		return gvk
+	}
 	if err != nil {
 		r.recordResult(Result{
 			OriginContext: oc,
 			Error:         err.Error(),
+			Code:          CodeInvalidGVK,
 		})
 	}
 	oc.GVK = gvk
@@ -97,7 +116,15 @@ func (r *populator) addGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *sch
 	// FOR has both an apply and delete runtime DAG
 	// WATCH has only an apply runtime DAG
 	if oc.FOWS == FOWFor || oc.FOWS == FOWWatch {
-		if err := r.cec.GetDAGCtx(oc.FOWS, oc.GVK, OperationApply).DAG.AddVertex(oc.VertexName, &rtdag.VertexContext{
+		dctx := r.cec.GetDAGCtx(oc.FOWS, oc.GVK, OperationApply)
+		if dctx == nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("dag not found for %s", oc.VertexName).Error(),
+			})
+			return gvk
+		}
+		if err := dctx.DAG.AddVertex(oc.VertexName, &rtdag.VertexContext{
 			VertexName: oc.VertexName,
 			Kind:       rtdag.RootVertexKind,
 			Function: ctrlcfgv1alpha1.Function{
@@ -115,7 +142,15 @@ func (r *populator) addGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *sch
 		}
 	}
 	if oc.FOWS == FOWFor {
-		if err := r.cec.GetDAGCtx(oc.FOWS, oc.GVK, OperationDelete).DAG.AddVertex(oc.VertexName, &rtdag.VertexContext{
+		dctx := r.cec.GetDAGCtx(oc.FOWS, oc.GVK, OperationDelete)
+		if dctx == nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("dag not found for %s", oc.VertexName).Error(),
+			})
+			return gvk
+		}
+		if err := dctx.DAG.AddVertex(oc.VertexName, &rtdag.VertexContext{
 			VertexName: oc.VertexName,
 			Kind:       rtdag.RootVertexKind,
 			Function: ctrlcfgv1alpha1.Function{
@@ -135,82 +170,65 @@ func (r *populator) addGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *sch
 	return gvk
 }
 
+func (r *populator) addUsedGvk(gvk *schema.GroupVersionKind) {
+	if gvk == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.usedGvks[*gvk] = struct{}{}
+}
+
 func (r *populator) addFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
+	if r.failed.has(oc) {
+		return
+	}
+	if r.cec.GetDAG(oc) == nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("dag not found for %s", oc.VertexName).Error(),
+		})
+		return
+	}
+	if v.Input != nil && len(v.Input.Resource.Raw) != 0 {
+		gvk, _ := r.ir.GetGVK(v.Input.Resource)
+		r.addUsedGvk(gvk)
+	}
 
 	// prepare the output context such that the runtime processing is easier
 	outputs := output.New()
 	gvkToVarName := map[string]string{}
-	for varName, outputCfg := range v.Output {
-		// prepare output context
-		gvk, err := meta.GetGVKFromRuntimeRawExtension(outputCfg.Resource)
-		if err != nil {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         err.Error(),
-			})
-		}
-		outputs.AddEntry(varName, &output.OutputInfo{
-			Internal:    outputCfg.Internal,
-			Conditioned: outputCfg.Conditioned,
-			GVK:         gvk,
+	fos, errs := getFunctionTypeHandler(v.Type).Outputs(r.ir, oc.VertexName, v)
+	for _, err := range errs {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
 		})
-		gvkToVarName[meta.GVKToString(gvk)] = varName
-
-		// add the runtime outputCtxt to the outputCtxt DAG for ensuring the output varibales are globally unique
-		// and to resolve and connect the graph
-		if err := r.gvar.GetDAG(FOWEntry{FOW: oc.FOWS, RootVertexName: oc.RootVertexName}).AddVariable(varName, &vardag.VariableContext{
-			OutputVertex:    oc.VertexName,
-			BlockIndex:      oc.BlockIndex,
-			BlockVertexName: oc.BlockVertexName,
-		}); err != nil {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         err.Error(),
-			})
+	}
+	for _, fo := range fos {
+		r.addUsedGvk(fo.GVK)
+		outputs.AddEntry(fo.VarName, &output.OutputInfo{
+			Internal:    fo.Internal,
+			Conditioned: fo.Conditioned,
+			GVK:         fo.GVK,
+		})
+		// the gvk mapping is only provided for the outputs in the output section
+		if v.Output != nil && fo.GVK != nil {
+			gvkToVarName[meta.GVKToString(fo.GVK)] = fo.VarName
 		}
 	}
+	// add the runtime outputCtxt to the outputCtxt DAG for ensuring the output varibales are globally unique
+	// and to resolve and connect the graph
 	// if no output, initialize the output Context variable with the vertexName
-	if v.Output == nil {
-		if v.Type == ctrlcfgv1alpha1.GoTemplateType {
-			if len(v.Input.Resource.Raw) != 0 {
-				gvk, err := meta.GetGVKFromRuntimeRawExtension(v.Input.Resource)
-				if err != nil {
-					r.recordResult(Result{
-						OriginContext: oc,
-						Error:         err.Error(),
-					})
-				}
-				outputs.AddEntry(oc.VertexName, &output.OutputInfo{
-					Internal:    false,
-					Conditioned: false,
-					GVK:         gvk,
-				})
-			}
-			// TODO what to do for a template ??? How do i get a GVK, is it also an external resource
-		} else {
-			if v.Input != nil && len(v.Input.Resource.Raw) != 0 {
-				gvk, err := meta.GetGVKFromRuntimeRawExtension(v.Input.Resource)
-				if err != nil {
-					r.recordResult(Result{
-						OriginContext: oc,
-						Error:         err.Error(),
-					})
-				}
-				outputs.AddEntry(oc.VertexName, &output.OutputInfo{
-					Internal:    true,
-					Conditioned: false,
-					GVK:         gvk,
-				})
-			} else {
-				outputs.AddEntry(oc.VertexName, &output.OutputInfo{
-					Internal:    true,
-					Conditioned: false,
-				})
-			}
-		}
-		// add the runtime outputCtxt to the outputCtxt DAG for ensuring the output varibales are globally unique
-		// and to resolve and connect the graph
-		if err := r.gvar.GetDAG(FOWEntry{FOW: oc.FOWS, RootVertexName: oc.RootVertexName}).AddVariable(oc.VertexName, &vardag.VariableContext{
+	varNames := make([]string, 0, len(fos))
+	for _, fo := range fos {
+		varNames = append(varNames, fo.VarName)
+	}
+	if len(varNames) == 0 {
+		varNames = append(varNames, oc.VertexName)
+	}
+	for _, varName := range varNames {
+		if err := r.gvar.GetDAG(FOWEntry{FOW: oc.FOWS, RootVertexName: oc.RootVertexName}).AddVariable(varName, &vardag.VariableContext{
 			OutputVertex:    oc.VertexName,
 			BlockIndex:      oc.BlockIndex,
 			BlockVertexName: oc.BlockVertexName,
@@ -253,6 +271,13 @@ func (r *populator) addFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function)
 		oc.BlockVertexName = oc.VertexName
 	}
 	blockDAG := r.cec.GetDAG(oc)
+	if blockDAG == nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("block dag not found for %s", oc.BlockVertexName).Error(),
+		})
+		return
+	}
 	if oc.BlockIndex == 0 {
 		// this is the initial block index and we need to add the vertex to both the main runtimeDAG
 		// and the block runtime DAG -> in the main runtimeDAG add the blockDAG
@@ -303,18 +328,70 @@ func (r *populator) addFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function)
 	}
 }
 
-/*
+func (r *populator) addServices(ctrlCfg *ctrlcfgv1alpha1.ControllerConfigSpec) {
+	for _, result := range addServices(r.cec, ctrlCfg) {
+		r.recordResult(result)
+	}
+}
+
+// addServices adds the services to the config execution context sorted by
+// name, such that the port allocation is deterministic. The service manifests
+// allocate the ports through the same function.
+func addServices(cec ConfigExecutionContext, ctrlCfg *ctrlcfgv1alpha1.ControllerConfigSpec) []Result {
+	result := []Result{}
+	for _, name := range ctrlCfg.GetServiceNames() {
+		fn := ctrlCfg.GetServices()[name]
+		if fn == nil {
+			// empty services are reported by the syntax validation
+			continue
+		}
+		if err := cec.AddService(name, *fn); err != nil {
+			result = append(result, Result{
+				OriginContext: &OriginContext{FOWS: FOWService, RootVertexName: name, Origin: OriginService, VertexName: name},
+				Error:         err.Error(),
+			})
+		}
+	}
+	return result
+}
+
 func (r *populator) addService(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
 	// we can safely consume the output as it was validated before
-	for _, outputCfg := range v.Output {
-		gvk, err := meta.GetGVKFromRuntimeRawExtension(outputCfg.Resource)
+	for _, varName := range sortedKeys(v.Output) {
+		outputCfg := v.Output[varName]
+		if outputCfg == nil {
+			continue
+		}
+		gvk, err := r.ir.GetGVK(outputCfg.Resource)
 		if err != nil {
 			r.recordResult(Result{
 				OriginContext: oc,
 				Error:         err.Error(),
 			})
+			continue
+		}
+		if err := r.cec.AddServiceGVK(oc.VertexName, gvk); err != nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         err.Error(),
+			})
+		}
+	}
+}
+
+// validateServices validates that every service output is used by a function
+func (r *populator) validateServices(ctrlCfg *ctrlcfgv1alpha1.ControllerConfigSpec) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	services := r.cec.GetServices()
+	for _, gvk := range sortedGVKs(services) {
+		gvk := gvk
+		svcCtx := services[gvk]
+		if _, ok := r.usedGvks[gvk]; !ok {
+			r.recordResult(Result{
+				OriginContext: &OriginContext{FOWS: FOWService, RootVertexName: svcCtx.Name, Origin: OriginService, VertexName: svcCtx.Name, GVK: &gvk},
+				Error:         fmt.Errorf("service output %s is not used by any function", meta.GVKToString(&gvk)).Error(),
+			})
 		}
-		r.cec.AddService(gvk, *v)
 	}
 }
-*/
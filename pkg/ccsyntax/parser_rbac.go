@@ -0,0 +1,218 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	verbGet    = "get"
+	verbList   = "list"
+	verbWatch  = "watch"
+	verbCreate = "create"
+	verbUpdate = "update"
+	verbPatch  = "patch"
+	verbDelete = "delete"
+
+	subresourceFinalizers = "finalizers"
+	subresourceStatus     = "status"
+)
+
+var (
+	readVerbs  = []string{verbGet, verbList, verbWatch}
+	writeVerbs = []string{verbCreate, verbUpdate, verbPatch, verbDelete}
+	// verbOrder provides a stable ordering of the verbs in a policy rule
+	verbOrder = map[string]int{
+		verbGet:    0,
+		verbList:   1,
+		verbWatch:  2,
+		verbCreate: 3,
+		verbUpdate: 4,
+		verbPatch:  5,
+		verbDelete: 6,
+	}
+)
+
+// GetPolicyRules returns the rbac policy rules the controller needs to
+// reconcile the resources used in the controller config
+func (r *parser) GetPolicyRules() ([]rbacv1.PolicyRule, []Result) {
+	rbac := &rbac{
+		verbs: map[schema.GroupResource]map[string]struct{}{},
+	}
+
+	ers, result := r.GetExternalResourceUsages()
+	for _, er := range ers {
+		rbac.addExternalResource(er)
+	}
+	return rbac.getPolicyRules(), result
+}
+
+// GetClusterRole returns a ClusterRole with the policy rules the controller needs
+func (r *parser) GetClusterRole(name string) (*rbacv1.ClusterRole, []Result) {
+	rules, result := r.GetPolicyRules()
+	return &rbacv1.ClusterRole{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "ClusterRole",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Rules: rules,
+	}, result
+}
+
+// GetRole returns a namespaced Role with the policy rules the controller needs
+func (r *parser) GetRole(name, namespace string) (*rbacv1.Role, []Result) {
+	rules, result := r.GetPolicyRules()
+	return &rbacv1.Role{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "Role",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Rules: rules,
+	}, result
+}
+
+var (
+	mrp sync.RWMutex
+	// resourcePlurals holds the resources of the kinds for which the plural
+	// cannot be guessed from the kind
+	resourcePlurals = map[schema.GroupKind]string{
+		{Group: "", Kind: "Endpoints"}:                 "endpoints",
+		{Group: "metrics.k8s.io", Kind: "PodMetrics"}:  "pods",
+		{Group: "metrics.k8s.io", Kind: "NodeMetrics"}: "nodes",
+	}
+)
+
+// RegisterResourcePlural registers the resource of a kind for which the
+// plural cannot be guessed from the kind
+func RegisterResourcePlural(gk schema.GroupKind, resource string) {
+	mrp.Lock()
+	defer mrp.Unlock()
+	resourcePlurals[gk] = resource
+}
+
+// kindToResource returns the group resource of the gvk, the plural is guessed
+// offline as the api server is not available
+func kindToResource(gvk *schema.GroupVersionKind) schema.GroupResource {
+	mrp.RLock()
+	resource, ok := resourcePlurals[gvk.GroupKind()]
+	mrp.RUnlock()
+	if ok {
+		return schema.GroupResource{Group: gvk.Group, Resource: resource}
+	}
+	gvr, _ := apimeta.UnsafeGuessKindToResource(*gvk)
+	// a kind ending in a vowel and y only gets an s, e.g. gateways
+	if kind := strings.ToLower(gvk.Kind); len(kind) > 1 && strings.HasSuffix(kind, "y") && strings.ContainsAny(kind[len(kind)-2:len(kind)-1], "aeiou") {
+		gvr.Resource = kind + "s"
+	}
+	return gvr.GroupResource()
+}
+
+type rbac struct {
+	mv    sync.RWMutex
+	verbs map[schema.GroupResource]map[string]struct{}
+}
+
+// addVerbs adds the verbs to the resource derived from the gvk, if a
+// subresource is supplied the verbs are added to the subresource
+func (r *rbac) addVerbs(gvk *schema.GroupVersionKind, subresource string, verbs ...string) {
+	if gvk == nil || gvk.Kind == "" {
+		return
+	}
+	gr := kindToResource(gvk)
+	if subresource != "" {
+		gr.Resource = gr.Resource + "/" + subresource
+	}
+
+	r.mv.Lock()
+	defer r.mv.Unlock()
+	if _, ok := r.verbs[gr]; !ok {
+		r.verbs[gr] = map[string]struct{}{}
+	}
+	for _, verb := range verbs {
+		r.verbs[gr][verb] = struct{}{}
+	}
+}
+
+func (r *rbac) addExternalResource(er *ExternalResource) {
+	for _, u := range er.Usages {
+		if u.Internal {
+			continue
+		}
+		switch u.Access {
+		case ResourceAccessWatch:
+			// own resources are watched through the owner reference
+			r.addVerbs(&er.GVK, "", readVerbs...)
+			if u.OriginContext.FOWS == FOWFor {
+				r.addVerbs(&er.GVK, subresourceFinalizers, verbUpdate)
+				r.addVerbs(&er.GVK, subresourceStatus, verbUpdate)
+			}
+		case ResourceAccessRead:
+			r.addVerbs(&er.GVK, "", readVerbs...)
+		case ResourceAccessWrite:
+			r.addVerbs(&er.GVK, "", writeVerbs...)
+		}
+	}
+}
+
+// getPolicyRules returns a policy rule per group resource sorted by group and
+// resource such that the generated manifest is stable
+func (r *rbac) getPolicyRules() []rbacv1.PolicyRule {
+	r.mv.RLock()
+	defer r.mv.RUnlock()
+	grs := make([]schema.GroupResource, 0, len(r.verbs))
+	for gr := range r.verbs {
+		grs = append(grs, gr)
+	}
+	sort.Slice(grs, func(i, j int) bool {
+		if grs[i].Group != grs[j].Group {
+			return grs[i].Group < grs[j].Group
+		}
+		return grs[i].Resource < grs[j].Resource
+	})
+
+	rules := make([]rbacv1.PolicyRule, 0, len(grs))
+	for _, gr := range grs {
+		verbs := make([]string, 0, len(r.verbs[gr]))
+		for verb := range r.verbs[gr] {
+			verbs = append(verbs, verb)
+		}
+		sort.Slice(verbs, func(i, j int) bool {
+			return verbOrder[verbs[i]] < verbOrder[verbs[j]]
+		})
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{gr.Group},
+			Resources: []string{gr.Resource},
+			Verbs:     verbs,
+		})
+	}
+	return rules
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"reflect"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// rbacConfig reconciles a pod and watches a gateway, the apply pipeline reads
+// a secret, writes a config map and a deployment and creates an internal service
+const rbacConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+watch:
+  gw:
+    resource:
+      apiVersion: gateway.networking.k8s.io/v1beta1
+      kind: Gateway
+    applyPipelineRef: apply
+pipelines:
+- name: apply
+  tasks:
+    secret:
+      type: query
+      input:
+        resource:
+          apiVersion: v1
+          kind: Secret
+    cm:
+      type: jq
+      input:
+        expression: $pod.metadata.name
+      output:
+        cm:
+          resource:
+            apiVersion: v1
+            kind: ConfigMap
+        svc:
+          internal: true
+          resource:
+            apiVersion: v1
+            kind: Service
+    deploy:
+      type: gotemplate
+      input:
+        resource:
+          apiVersion: apps/v1
+          kind: Deployment
+          metadata:
+            name: '{{ .pod.metadata.name }}'
+- name: delete
+`
+
+func TestKindToResource(t *testing.T) {
+	cases := map[string]struct {
+		gvk  schema.GroupVersionKind
+		want schema.GroupResource
+	}{
+		"Regular": {
+			gvk:  schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			want: schema.GroupResource{Resource: "pods"},
+		},
+		"ConsonantY": {
+			gvk:  schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"},
+			want: schema.GroupResource{Group: "networking.k8s.io", Resource: "networkpolicies"},
+		},
+		"VowelY": {
+			gvk:  schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1beta1", Kind: "Gateway"},
+			want: schema.GroupResource{Group: "gateway.networking.k8s.io", Resource: "gateways"},
+		},
+		"Endpoints": {
+			gvk:  schema.GroupVersionKind{Version: "v1", Kind: "Endpoints"},
+			want: schema.GroupResource{Resource: "endpoints"},
+		},
+		"PodMetrics": {
+			gvk:  schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetrics"},
+			want: schema.GroupResource{Group: "metrics.k8s.io", Resource: "pods"},
+		},
+		"Registered": {
+			gvk:  schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Chassis"},
+			want: schema.GroupResource{Group: "example.com", Resource: "chassis"},
+		},
+	}
+	RegisterResourcePlural(schema.GroupKind{Group: "example.com", Kind: "Chassis"}, "chassis")
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := kindToResource(&c.gvk); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPolicyRules(t *testing.T) {
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal([]byte(rbacConfig), cfg); err != nil {
+		t.Fatal(err)
+	}
+	p, result := NewParser("rbac", cfg)
+	if len(result) != 0 {
+		t.Fatalf("unexpected validation results: %v", result)
+	}
+
+	read := []string{verbGet, verbList, verbWatch}
+	write := []string{verbCreate, verbUpdate, verbPatch, verbDelete}
+	rules := []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: write},
+		{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: read},
+		{APIGroups: []string{""}, Resources: []string{"pods/finalizers"}, Verbs: []string{verbUpdate}},
+		{APIGroups: []string{""}, Resources: []string{"pods/status"}, Verbs: []string{verbUpdate}},
+		{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: read},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: write},
+		{APIGroups: []string{"gateway.networking.k8s.io"}, Resources: []string{"gateways"}, Verbs: read},
+	}
+
+	cases := map[string]struct {
+		get       func() (string, string, string, []rbacv1.PolicyRule, []Result)
+		kind      string
+		name      string
+		namespace string
+	}{
+		"PolicyRules": {
+			get: func() (string, string, string, []rbacv1.PolicyRule, []Result) {
+				rules, result := p.GetPolicyRules()
+				return "", "", "", rules, result
+			},
+		},
+		"ClusterRole": {
+			get: func() (string, string, string, []rbacv1.PolicyRule, []Result) {
+				cr, result := p.GetClusterRole("rbac")
+				return cr.Kind, cr.Name, cr.Namespace, cr.Rules, result
+			},
+			kind: "ClusterRole",
+			name: "rbac",
+		},
+		"Role": {
+			get: func() (string, string, string, []rbacv1.PolicyRule, []Result) {
+				role, result := p.GetRole("rbac", "default")
+				return role.Kind, role.Name, role.Namespace, role.Rules, result
+			},
+			kind:      "Role",
+			name:      "rbac",
+			namespace: "default",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			kind, name, namespace, got, result := c.get()
+			if len(result) != 0 {
+				t.Fatalf("unexpected results: %v", result)
+			}
+			if kind != c.kind || name != c.name || namespace != c.namespace {
+				t.Errorf("got %s %s/%s, want %s %s/%s", kind, namespace, name, c.kind, c.namespace, c.name)
+			}
+			if !reflect.DeepEqual(got, rules) {
+				t.Errorf("got rules %v, want %v", got, rules)
+			}
+		})
+	}
+}
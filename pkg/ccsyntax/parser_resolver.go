@@ -17,20 +17,25 @@ limitations under the License.
 package ccsyntax
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
 	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"github.com/fnrunner/fnutils/pkg/meta"
 )
 
-func (r *parser) resolve(ceCtx ConfigExecutionContext, gvar GlobalVariable) []Result {
+func (r *parser) resolve(ctx context.Context, ceCtx ConfigExecutionContext, gvar GlobalVariable, failed *failedVertices) []Result {
 	rs := &resolver{
+		ir:     r.ir,
 		ceCtx:  ceCtx,
 		gvar:   gvar,
+		failed: failed,
 		result: []Result{},
 	}
 
 	fnc := &WalkConfig{
+		ctx: ctx,
 		//gvkObjectFn: rs.resolveGvk,
 		functionFn: rs.resolveFunction,
 	}
@@ -42,8 +47,10 @@ func (r *parser) resolve(ceCtx ConfigExecutionContext, gvar GlobalVariable) []Re
 }
 
 type resolver struct {
+	ir     *configIR
 	ceCtx  ConfigExecutionContext
 	gvar   GlobalVariable
+	failed *failedVertices
 	mr     sync.RWMutex
 	result []Result
 }
@@ -55,37 +62,66 @@ func (r *resolver) recordResult(result Result) {
 }
 
 func (r *resolver) resolveFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
-	for localVarName, v := range v.Vars {
+	if r.failed.has(oc) {
+		return
+	}
+	for _, localVarName := range sortedKeys(v.Vars) {
 		oc.LocalVarName = localVarName
-		r.resolveRefs(oc, v)
+		r.resolveRefs(oc, v.Vars[localVarName])
 	}
 
 	if v.HasBlock() {
 		r.resolveBlock(oc, v.Block)
 	}
 
-	if v.Input.Selector != nil {
-		for k, v := range v.Input.Selector.MatchLabels {
-			r.resolveRefs(oc, k)
-			r.resolveRefs(oc, v)
-		}
+	for _, ref := range getFunctionTypeHandler(v.Type).References(v) {
+		r.resolveRefs(oc, ref)
 	}
-
-	if v.Input.Key != "" {
-		r.resolveRefs(oc, v.Input.Key)
+	if len(v.DependsOn) > 0 {
+		r.resolveDependsOn(oc, v.DependsOn)
+	}
+	if v.ServiceRef != nil {
+		r.resolveServiceRef(oc, v)
 	}
-	if v.Input.Value != "" {
-		r.resolveRefs(oc, v.Input.Value)
+}
+
+// resolveServiceRef validates the service exists and that the service output
+// gvk is produced by the function
+func (r *resolver) resolveServiceRef(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
+	svcCtx := r.ceCtx.GetService(v.ServiceRef.Name)
+	if svcCtx == nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("service %s does not exist", v.ServiceRef.Name).Error(),
+		})
+		return
 	}
-	if v.Input.Expression != "" {
-		r.resolveRefs(oc, v.Input.Expression)
+	svcOutput, ok := svcCtx.Fn.Output[v.ServiceRef.Output]
+	if !ok || svcOutput == nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("service %s has no output %s", v.ServiceRef.Name, v.ServiceRef.Output).Error(),
+		})
+		return
 	}
-	for _, v := range v.Input.GenericInput {
-		r.resolveRefs(oc, v)
+	svcGvk, err := r.ir.GetGVK(svcOutput.Resource)
+	if err != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+		})
+		return
 	}
-	if len(v.DependsOn) > 0 {
-		r.resolveDependsOn(oc, v.DependsOn)
+	for _, output := range v.Output {
+		gvk, err := r.ir.GetGVK(output.Resource)
+		if err == nil && *gvk == *svcGvk {
+			return
+		}
 	}
+	r.recordResult(Result{
+		OriginContext: oc,
+		Error:         fmt.Errorf("service %s output %s gvk %s does not match the function output", v.ServiceRef.Name, v.ServiceRef.Output, meta.GVKToString(svcGvk)).Error(),
+	})
 }
 
 func (r *resolver) resolveBlock(oc *OriginContext, v ctrlcfgv1alpha1.Block) {
@@ -108,14 +144,13 @@ func (r *resolver) resolveBlock(oc *OriginContext, v ctrlcfgv1alpha1.Block) {
 }
 
 func (r *resolver) resolveRefs(oc *OriginContext, s string) {
-	rfs := NewReferences()
-	refs := rfs.GetReferences(s)
+	refs := r.ir.GetReferences(s)
 
 	for _, ref := range refs {
 		// for regular values we resolve the variables
 		// for variables that start with _ this is a special case and
 		// should only be used within a jq construct
-		if ref.Kind == RegularReferenceKind && ref.Value[0] != '_' {
+		if ref.Kind == RegularReferenceKind && ref.Value != "" && ref.Value[0] != '_' {
 			//d := r.ceCtx.GetDAG(oc)
 			// get the vertexContext from the function
 			//vc := d.GetVertex(oc.VertexName)
@@ -131,6 +166,7 @@ func (r *resolver) resolveRefs(oc *OriginContext, s string) {
 				r.recordResult(Result{
 					OriginContext: oc,
 					Error:         fmt.Errorf("cannot resolve %s", ref.Value).Error(),
+					Code:          CodeUnresolvedReference,
 				})
 			}
 		}
@@ -138,8 +174,12 @@ func (r *resolver) resolveRefs(oc *OriginContext, s string) {
 }
 
 func (r *resolver) resolveDependsOn(oc *OriginContext, vertexNames []string) {
+	dctx := r.ceCtx.GetDAGCtx(oc.FOWS, oc.GVK, oc.Operation)
+	if dctx == nil {
+		return
+	}
 	for _, vertexName := range vertexNames {
-		if r.ceCtx.GetDAGCtx(oc.FOWS, oc.GVK, oc.Operation).DAG.GetVertex(vertexName) == nil {
+		if dctx.DAG.GetVertex(vertexName) == nil {
 			r.recordResult(Result{
 				OriginContext: oc,
 				Error:         fmt.Errorf("vertex in depndsOn does not exist %s", vertexName).Error(),
@@ -17,12 +17,101 @@ limitations under the License.
 package ccsyntax
 
 import (
+	"fmt"
+
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type Result struct {
 	OriginContext *OriginContext `json:"inline" yaml:"inline"`
 	Error         string         `json:"error,omitempty" yaml:"error,omitempty"`
+	// Severity of the result, an empty severity is treated as an error
+	Severity Severity `json:"severity,omitempty" yaml:"severity,omitempty"`
+	// Code identifies the kind of finding, results without code are
+	// identified by their error
+	Code ResultCode `json:"code,omitempty" yaml:"code,omitempty"`
+	// Contexts lists all the origin contexts the finding applies to, the
+	// OriginContext is the first of them
+	Contexts []*OriginContext `json:"contexts,omitempty" yaml:"contexts,omitempty"`
+}
+
+type ResultCode string
+
+const (
+	CodeMissingGVK          ResultCode = "MissingGVK"
+	CodeInvalidGVK          ResultCode = "InvalidGVK"
+	CodeUnresolvedReference ResultCode = "UnresolvedReference"
+	CodeLimitExceeded       ResultCode = "LimitExceeded"
+	CodeDependencyCycle     ResultCode = "DependencyCycle"
+	CodeCanceled            ResultCode = "Canceled"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// IsError returns true if the result is an error
+func (r Result) IsError() bool {
+	return r.Severity == "" || r.Severity == SeverityError
+}
+
+// HasErrors returns true if one of the results is an error
+func HasErrors(results []Result) bool {
+	for _, result := range results {
+		if result.IsError() {
+			return true
+		}
+	}
+	return false
+}
+
+// DeduplicateResults merges the results that report the same finding on the
+// same element of the config. The config is walked once per apply and delete
+// pipeline and once per phase, so the same finding is reported multiple times.
+// The merged result keeps the order of the first occurrence and lists all the
+// origin contexts it was reported in.
+func DeduplicateResults(results []Result) []Result {
+	if results == nil {
+		return nil
+	}
+	deduped := make([]Result, 0, len(results))
+	idx := map[string]int{}
+	for _, result := range results {
+		key := fmt.Sprintf("%s|%s|%s", result.OriginContext.elementKey(), result.Code, result.Error)
+		i, ok := idx[key]
+		if !ok {
+			idx[key] = len(deduped)
+			result.Contexts = append([]*OriginContext{}, result.Contexts...)
+			if len(result.Contexts) == 0 && result.OriginContext != nil {
+				result.Contexts = append(result.Contexts, result.OriginContext)
+			}
+			deduped = append(deduped, result)
+			continue
+		}
+		contexts := result.Contexts
+		if len(contexts) == 0 && result.OriginContext != nil {
+			contexts = []*OriginContext{result.OriginContext}
+		}
+		for _, oc := range contexts {
+			if !containsOriginContext(deduped[i].Contexts, oc) {
+				deduped[i].Contexts = append(deduped[i].Contexts, oc)
+			}
+		}
+	}
+	return deduped
+}
+
+func containsOriginContext(ocs []*OriginContext, oc *OriginContext) bool {
+	for _, o := range ocs {
+		if o.String() == oc.String() {
+			return true
+		}
+	}
+	return false
 }
 
 type recordResultFn func(Result)
@@ -43,6 +132,36 @@ type OriginContext struct {
 	LocalVars       map[string]string        `json:"localVars,omitempty" yaml:"localvarName,omitempty"`
 }
 
+// elementKey identifies the element of the config the origin context points
+// to, independent of the walk that reached it
+func (r *OriginContext) elementKey() string {
+	if r == nil {
+		return ""
+	}
+	switch r.Origin {
+	case OriginFow:
+		return fmt.Sprintf("%s/%s/%s", r.FOWS, r.VertexName, r.Pipeline)
+	case OriginService:
+		return fmt.Sprintf("%s/%s", r.Origin, r.VertexName)
+	case OriginVariable, OriginFunction:
+		return fmt.Sprintf("%s/%s/%s/%s/%s", r.Pipeline, r.Origin, r.BlockVertexName, r.VertexName, r.LocalVarName)
+	}
+	return r.String()
+}
+
+// String returns the origin context with all its fields
+func (r *OriginContext) String() string {
+	if r == nil {
+		return ""
+	}
+	gvk := ""
+	if r.GVK != nil {
+		gvk = r.GVK.String()
+	}
+	return fmt.Sprintf("{%s %s %s %s %s %s %t %d %s %s %s}", r.FOWS, r.RootVertexName, gvk, r.Operation, r.Pipeline, r.Origin,
+		r.Block, r.BlockIndex, r.BlockVertexName, r.VertexName, r.LocalVarName)
+}
+
 func (in *OriginContext) DeepCopy() *OriginContext {
 	if in == nil {
 		return nil
@@ -54,6 +173,16 @@ func (in *OriginContext) DeepCopy() *OriginContext {
 
 func (in *OriginContext) DeepCopyInto(out *OriginContext) {
 	*out = *in
+	if in.GVK != nil {
+		gvk := *in.GVK
+		out.GVK = &gvk
+	}
+	if in.LocalVars != nil {
+		out.LocalVars = make(map[string]string, len(in.LocalVars))
+		for k, v := range in.LocalVars {
+			out.LocalVars[k] = v
+		}
+	}
 }
 
 type FOWS string
@@ -0,0 +1,170 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"strings"
+
+	fnrunv1alpha1 "github.com/fnrunner/fnruntime/apis/fnrun/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const defaultServicePortName = "grpc"
+
+// ServiceManifest contains the manifests to deploy a service
+type ServiceManifest struct {
+	Name       string
+	Deployment *appsv1.Deployment
+	Service    *corev1.Service
+}
+
+// GetServiceManifests renders a Deployment and Service for every service in
+// the controller config. The ports are allocated like the parser allocates
+// them, such that they match the ports of the config execution context.
+func (r *parser) GetServiceManifests(namespace string) ([]*ServiceManifest, []Result) {
+	ceCtx := NewConfigExecutionContext(r.controllerName)
+	result := addServices(ceCtx, r.cCfg)
+	manifests := []*ServiceManifest{}
+	for _, svcName := range r.cCfg.GetServiceNames() {
+		oc := &OriginContext{FOWS: FOWService, RootVertexName: svcName, Origin: OriginService, VertexName: svcName}
+		fn := r.cCfg.GetServices()[svcName]
+		if fn == nil {
+			result = append(result, Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("a service cannot be empty").Error(),
+			})
+			continue
+		}
+		name := strings.ToLower(fmt.Sprintf("%s-%s", r.controllerName, svcName))
+		if msgs := validation.IsDNS1123Label(name); len(msgs) > 0 {
+			result = append(result, Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("invalid service name %s: %s", name, strings.Join(msgs, ", ")).Error(),
+			})
+			continue
+		}
+		svcCtx := ceCtx.GetService(svcName)
+		if svcCtx == nil {
+			result = append(result, Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("service %s not found in the config execution context", svcName).Error(),
+			})
+			continue
+		}
+		port := int32(svcCtx.Port)
+		portName := fn.PortName
+		if portName == "" {
+			portName = defaultServicePortName
+		}
+		replicas := int32(1)
+		if fn.Replicas != nil {
+			replicas = *fn.Replicas
+		}
+		readinessProbe := fn.ReadinessProbe
+		if readinessProbe == nil {
+			readinessProbe = &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					GRPC: &corev1.GRPCAction{
+						Port:    port,
+						Service: &name,
+					},
+				},
+			}
+		}
+		resources := corev1.ResourceRequirements{}
+		if fn.Resources != nil {
+			resources = *fn.Resources
+		}
+		labels := map[string]string{
+			fnrunv1alpha1.FunctionLabelKey: name,
+		}
+
+		manifests = append(manifests, &ServiceManifest{
+			Name: svcName,
+			Deployment: &appsv1.Deployment{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: appsv1.SchemeGroupVersion.String(),
+					Kind:       "Deployment",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      name,
+					Labels:    labels,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{
+						MatchLabels: labels,
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: labels,
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  fnrunv1alpha1.FnContainerName,
+									Image: fn.Image,
+									Ports: []corev1.ContainerPort{
+										{
+											Name:          portName,
+											ContainerPort: port,
+											Protocol:      corev1.ProtocolTCP,
+										},
+									},
+									Env:            fn.Env,
+									EnvFrom:        fn.EnvFrom,
+									Resources:      resources,
+									ReadinessProbe: readinessProbe,
+									LivenessProbe:  fn.LivenessProbe,
+								},
+							},
+						},
+					},
+				},
+			},
+			Service: &corev1.Service{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "Service",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      name,
+					Labels:    labels,
+				},
+				Spec: corev1.ServiceSpec{
+					Selector: labels,
+					Ports: []corev1.ServicePort{
+						{
+							Name:       portName,
+							Port:       port,
+							TargetPort: intstr.FromString(portName),
+							Protocol:   corev1.ProtocolTCP,
+						},
+					},
+				},
+			},
+		})
+	}
+	return manifests, result
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"strings"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// the empty service a is not added to the config execution context and does
+// not get a port
+const serviceManifestsConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+pipelines:
+- name: apply
+  tasks:
+    nodes:
+      type: servicecall
+      serviceRef:
+        name: b
+        output: nodes
+      output:
+        nodes:
+          resource:
+            apiVersion: topo.yndd.io/v1alpha1
+            kind: Node
+    links:
+      type: servicecall
+      serviceRef:
+        name: c
+        output: links
+      output:
+        links:
+          resource:
+            apiVersion: topo.yndd.io/v1alpha1
+            kind: Link
+- name: delete
+services:
+  a: null
+  b:
+    type: container
+    image: b-image
+    output:
+      nodes:
+        resource:
+          apiVersion: topo.yndd.io/v1alpha1
+          kind: Node
+  c:
+    type: container
+    image: c-image
+    output:
+      links:
+        resource:
+          apiVersion: topo.yndd.io/v1alpha1
+          kind: Link
+`
+
+func TestServiceManifestPorts(t *testing.T) {
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal([]byte(serviceManifestsConfig), cfg); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := NewParser("test", cfg)
+	ceCtx, result := p.Parse()
+	if len(result) != 0 {
+		t.Fatal(result)
+	}
+	manifests, result := p.GetServiceManifests("default")
+	if len(result) != 1 || result[0].OriginContext.VertexName != "a" || result[0].Error != "a service cannot be empty" {
+		t.Errorf("expected a result for the empty service, got: %v", result)
+	}
+	if len(manifests) != 2 {
+		t.Fatalf("got %d manifests, want 2", len(manifests))
+	}
+	for _, m := range manifests {
+		want := int32(ceCtx.GetService(m.Name).Port)
+		if got := m.Deployment.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort; got != want {
+			t.Errorf("service %s: got port %d, want %d", m.Name, got, want)
+		}
+		if got := m.Service.Spec.Ports[0].Port; got != want {
+			t.Errorf("service %s: got service port %d, want %d", m.Name, got, want)
+		}
+	}
+}
+
+func TestServiceManifestsUnparsedConfig(t *testing.T) {
+	// a reference that does not resolve fails the parsing of the pipeline,
+	// the services are rendered regardless
+	config := strings.Replace(serviceManifestsConfig, "  tasks:\n", "  tasks:\n    unresolved:\n      type: jq\n      input:\n        expression: $missing\n", 1)
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal([]byte(config), cfg); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := NewParser("test", cfg)
+	if _, result := p.Parse(); len(result) == 0 {
+		t.Fatal("expected the config to fail parsing")
+	}
+	manifests, _ := p.GetServiceManifests("default")
+	if len(manifests) != 2 {
+		t.Fatalf("got %d manifests, want 2", len(manifests))
+	}
+}
+
+func TestServiceExecutionSettings(t *testing.T) {
+	for name, settings := range map[string]string{
+		"Timeout": "    timeout: 30s\n",
+		"Retry":   "    retry:\n      count: 3\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := strings.Replace(serviceManifestsConfig, "    image: b-image\n", "    image: b-image\n"+settings, 1)
+			cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+			if err := yaml.Unmarshal([]byte(config), cfg); err != nil {
+				t.Fatal(err)
+			}
+			_, result := NewParser("test", cfg)
+			found := false
+			for _, res := range result {
+				if res.OriginContext.VertexName == "b" && res.Error == "cannot use timeout or retry in services" {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected the %s of service b to be rejected, got: %v", name, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"sort"
+
+	"github.com/fnrunner/fnutils/pkg/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// the config is stored in maps, the helpers below provide a stable order
+// such that the parser output and results are identical for identical input
+
+// sortedKeys returns the keys of the map sorted by name
+func sortedKeys[K ~string, T any](m map[K]T) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// sortedGVKs returns the gvks of the map sorted by their string representation
+func sortedGVKs[T any](m map[schema.GroupVersionKind]T) []schema.GroupVersionKind {
+	gvks := make([]schema.GroupVersionKind, 0, len(m))
+	for gvk := range m {
+		gvks = append(gvks, gvk)
+	}
+	sort.Slice(gvks, func(i, j int) bool {
+		return meta.GVKToString(&gvks[i]) < meta.GVKToString(&gvks[j])
+	})
+	return gvks
+}
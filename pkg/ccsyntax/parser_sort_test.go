@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// parseOutput returns the raw outputs of a fresh parser for the config, the
+// outputs are not sorted such that a different order is detected
+func parseOutput(t *testing.T, b []byte) string {
+	t.Helper()
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		t.Fatal(err)
+	}
+	p, result := NewParser("test", cfg)
+	if len(result) != 0 {
+		t.Fatal(result)
+	}
+	var out bytes.Buffer
+	ceCtx, result := p.Parse()
+	if ceCtx != nil {
+		ceCtx.Fprint(&out)
+	}
+	fmt.Fprintf(&out, "results: %v\n", result)
+	for _, image := range p.GetImages() {
+		fmt.Fprintf(&out, "image: %v\n", *image)
+	}
+	gvks, result := p.GetExternalResources()
+	for _, gvk := range gvks {
+		fmt.Fprintf(&out, "external resource: %v\n", *gvk)
+	}
+	fmt.Fprintf(&out, "results: %v\n", result)
+	return out.String()
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	for _, name := range []string{"topo4.yaml", "upf.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join("..", "..", "examples", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := parseOutput(t, b)
+			for i := 0; i < 10; i++ {
+				if got := parseOutput(t, b); got != want {
+					t.Fatalf("run %d: the output differs from the first run\ngot:\n%s\nwant:\n%s", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"github.com/go-logr/logr/funcr"
+	"sigs.k8s.io/yaml"
+)
+
+// the task refers to a variable that does not exist, which fails the connect
+// phase
+const unresolvedConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+pipelines:
+- name: apply
+  tasks:
+    name:
+      type: jq
+      input:
+        expression: $missing.metadata.name
+      output:
+        name:
+          resource:
+            apiVersion: v1
+            kind: ConfigMap
+- name: delete
+`
+
+func newTestParser(t *testing.T, config string, opts ...Option) Parser {
+	t.Helper()
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal([]byte(config), cfg); err != nil {
+		t.Fatal(err)
+	}
+	p, result := NewParser("test", cfg, opts...)
+	if len(result) != 0 {
+		t.Fatalf("unexpected validation results: %v", result)
+	}
+	return p
+}
+
+func TestParseContextCanceled(t *testing.T) {
+	for name, config := range map[string]string{"Valid": limitsConfig, "Unresolved": unresolvedConfig} {
+		t.Run(name, func(t *testing.T) {
+			p := newTestParser(t, config, WithKeepGoing())
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			ceCtx, result := p.ParseContext(ctx)
+			if ceCtx != nil {
+				t.Error("expected no config execution context")
+			}
+			if len(result) == 0 || result[len(result)-1].Code != CodeCanceled {
+				t.Fatalf("expected a canceled result, got: %v", result)
+			}
+			// the parser can be used again after a cancellation
+			if ceCtx, _ := p.ParseAll(); ceCtx == nil {
+				t.Error("expected a config execution context")
+			}
+		})
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		p := newTestParser(t, unresolvedConfig)
+		ceCtx, result := p.Parse()
+		if ceCtx != nil {
+			t.Error("expected no config execution context")
+		}
+		if !hasResult(result, "name", "variable not found in gvar dag, varName: missing") {
+			t.Errorf("expected an unresolved reference, got: %v", result)
+		}
+	})
+	t.Run("WithKeepGoing", func(t *testing.T) {
+		p := newTestParser(t, unresolvedConfig, WithKeepGoing())
+		ceCtx, result := p.Parse()
+		if ceCtx == nil {
+			t.Fatal("expected a partial config execution context")
+		}
+		if !hasResult(result, "name", "variable not found in gvar dag, varName: missing") {
+			t.Errorf("expected an unresolved reference, got: %v", result)
+		}
+		if _, want := p.ParseAll(); len(want) != len(result) {
+			t.Errorf("got %v, want the results of ParseAll %v", result, want)
+		}
+	})
+	t.Run("WithLogger", func(t *testing.T) {
+		msgs := []string{}
+		l := funcr.New(func(prefix, args string) { msgs = append(msgs, args) }, funcr.Options{})
+		p := newTestParser(t, unresolvedConfig, WithLogger(l))
+		p.Parse()
+		if !strings.Contains(strings.Join(msgs, "\n"), "connect failed") {
+			t.Errorf("expected the failed phase to be logged, got: %v", msgs)
+		}
+	})
+}
+
+func hasResult(result []Result, vertexName, msg string) bool {
+	for _, r := range result {
+		if r.OriginContext != nil && r.OriginContext.VertexName == vertexName && r.Error == msg {
+			return true
+		}
+	}
+	return false
+}
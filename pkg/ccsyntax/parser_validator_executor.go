@@ -0,0 +1,290 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"strings"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// validateExecutor validates that an external function uses either an image
+// or exec. Wasm functions only support a module reference in the image, which
+// is either an OCI reference or a path to a .wasm file.
+func validateExecutor(t ctrlcfgv1alpha1.FunctionType, v *ctrlcfgv1alpha1.Executor) []error {
+	switch {
+	case v.Image == "" && v.Exec == "":
+		return []error{fmt.Errorf("%s functions need an image or exec", t)}
+	case v.Image != "" && v.Exec != "":
+		return []error{fmt.Errorf("%s functions cannot have both image and exec", t)}
+	}
+
+	if t == ctrlcfgv1alpha1.WasmType {
+		if v.Exec != "" {
+			return []error{fmt.Errorf("exec is not supported in %s functions, use image to reference the module", t)}
+		}
+		if !isWasmPath(v.Image) {
+			if _, err := parseImageReference(v.Image); err != nil {
+				return []error{fmt.Errorf("invalid wasm module %s, must be an OCI reference or a .wasm path: %s", v.Image, err.Error())}
+			}
+		}
+		return nil
+	}
+
+	if v.Exec != "" {
+		if _, err := splitCommand(v.Exec); err != nil {
+			return []error{fmt.Errorf("invalid exec %s: %s", v.Exec, err.Error())}
+		}
+	}
+	return nil
+}
+
+// validateRuntimeSettings validates the resources, env, timeout and retry of
+// an external function. The settings are carried in the function of the
+// runtime vertex context such that the runtime can enforce them.
+func (r *vs) validateRuntimeSettings(oc *OriginContext, v *ctrlcfgv1alpha1.Executor) {
+	r.validateResources(oc, v.Resources)
+	r.validateEnv(oc, v.Env, v.EnvFrom)
+	if v.Timeout != nil && v.Timeout.Duration <= 0 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("timeout must be positive, got: %s", v.Timeout.Duration).Error(),
+		})
+	}
+	if v.Retry != nil {
+		if v.Retry.Count < 0 {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("retry count cannot be negative, got: %d", v.Retry.Count).Error(),
+			})
+		}
+		if v.Retry.Backoff != nil && v.Retry.Backoff.Duration < 0 {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("retry backoff cannot be negative, got: %s", v.Retry.Backoff.Duration).Error(),
+			})
+		}
+	}
+}
+
+// validateServiceSettings validates the replicas, port name and probes of a service
+func (r *vs) validateServiceSettings(oc *OriginContext, v *ctrlcfgv1alpha1.ServiceSettings) {
+	if v.Replicas != nil && *v.Replicas < 1 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("replicas must be at least 1, got: %d", *v.Replicas).Error(),
+		})
+	}
+	if v.PortName != "" {
+		for _, msg := range validation.IsValidPortName(v.PortName) {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("invalid port name %s: %s", v.PortName, msg).Error(),
+			})
+		}
+	}
+	r.validateProbe(oc, "readinessProbe", v.ReadinessProbe)
+	r.validateProbe(oc, "livenessProbe", v.LivenessProbe)
+}
+
+func (r *vs) validateProbe(oc *OriginContext, name string, v *corev1.Probe) {
+	if v == nil {
+		return
+	}
+	handlers := 0
+	if v.Exec != nil {
+		handlers++
+		if len(v.Exec.Command) == 0 {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("%s exec needs a command", name).Error(),
+			})
+		}
+	}
+	if v.HTTPGet != nil {
+		handlers++
+		r.validateProbePort(oc, name, v.HTTPGet.Port.IntValue(), v.HTTPGet.Port.StrVal)
+	}
+	if v.TCPSocket != nil {
+		handlers++
+		r.validateProbePort(oc, name, v.TCPSocket.Port.IntValue(), v.TCPSocket.Port.StrVal)
+	}
+	if v.GRPC != nil {
+		handlers++
+		r.validateProbePort(oc, name, int(v.GRPC.Port), "")
+	}
+	if handlers != 1 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("%s must have exactly 1 handler (exec, httpGet, tcpSocket or grpc), got: %d", name, handlers).Error(),
+		})
+	}
+	if v.InitialDelaySeconds < 0 || v.TimeoutSeconds < 0 || v.PeriodSeconds < 0 ||
+		v.SuccessThreshold < 0 || v.FailureThreshold < 0 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("%s cannot have negative delays, periods or thresholds", name).Error(),
+		})
+	}
+}
+
+func (r *vs) validateProbePort(oc *OriginContext, name string, port int, portName string) {
+	if portName != "" {
+		for _, msg := range validation.IsValidPortName(portName) {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("%s invalid port name %s: %s", name, portName, msg).Error(),
+			})
+		}
+		return
+	}
+	for _, msg := range validation.IsValidPortNum(port) {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("%s invalid port %d: %s", name, port, msg).Error(),
+		})
+	}
+}
+
+// validateResources validates that the requests do not exceed the limits
+func (r *vs) validateResources(oc *OriginContext, v *corev1.ResourceRequirements) {
+	if v == nil {
+		return
+	}
+	for _, name := range sortedKeys(v.Requests) {
+		request := v.Requests[name]
+		if request.Sign() < 0 {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("resource request %s cannot be negative, got: %s", name, request.String()).Error(),
+			})
+		}
+		if limit, ok := v.Limits[name]; ok && request.Cmp(limit) > 0 {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("resource request %s must be less than or equal to the limit, got request: %s, limit: %s", name, request.String(), limit.String()).Error(),
+			})
+		}
+	}
+	for _, name := range sortedKeys(v.Limits) {
+		limit := v.Limits[name]
+		if limit.Sign() < 0 {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("resource limit %s cannot be negative, got: %s", name, limit.String()).Error(),
+			})
+		}
+	}
+}
+
+// validateEnv validates the environment variables and their ConfigMap and
+// Secret sources
+func (r *vs) validateEnv(oc *OriginContext, env []corev1.EnvVar, envFrom []corev1.EnvFromSource) {
+	for _, e := range env {
+		for _, msg := range validation.IsEnvVarName(e.Name) {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("invalid env name %s: %s", e.Name, msg).Error(),
+			})
+		}
+		if e.ValueFrom == nil {
+			continue
+		}
+		if e.Value != "" {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("env %s cannot have both value and valueFrom", e.Name).Error(),
+			})
+		}
+		sources := 0
+		if e.ValueFrom.ConfigMapKeyRef != nil {
+			sources++
+			r.validateKeyRef(oc, e.Name, "configMapKeyRef", e.ValueFrom.ConfigMapKeyRef.Name, e.ValueFrom.ConfigMapKeyRef.Key)
+		}
+		if e.ValueFrom.SecretKeyRef != nil {
+			sources++
+			r.validateKeyRef(oc, e.Name, "secretKeyRef", e.ValueFrom.SecretKeyRef.Name, e.ValueFrom.SecretKeyRef.Key)
+		}
+		if e.ValueFrom.FieldRef != nil {
+			sources++
+		}
+		if e.ValueFrom.ResourceFieldRef != nil {
+			sources++
+		}
+		if sources != 1 {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("env %s valueFrom must have exactly 1 source, got: %d", e.Name, sources).Error(),
+			})
+		}
+	}
+	for _, e := range envFrom {
+		if e.Prefix != "" {
+			for _, msg := range validation.IsEnvVarName(e.Prefix) {
+				r.recordResult(Result{
+					OriginContext: oc,
+					Error:         fmt.Errorf("invalid envFrom prefix %s: %s", e.Prefix, msg).Error(),
+				})
+			}
+		}
+		switch {
+		case e.ConfigMapRef != nil && e.SecretRef != nil:
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("envFrom cannot have both configMapRef and secretRef").Error(),
+			})
+		case e.ConfigMapRef != nil:
+			r.validateObjectName(oc, "envFrom configMapRef", e.ConfigMapRef.Name)
+		case e.SecretRef != nil:
+			r.validateObjectName(oc, "envFrom secretRef", e.SecretRef.Name)
+		default:
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("envFrom needs a configMapRef or secretRef").Error(),
+			})
+		}
+	}
+}
+
+func (r *vs) validateKeyRef(oc *OriginContext, envName, kind, name, key string) {
+	r.validateObjectName(oc, fmt.Sprintf("env %s %s", envName, kind), name)
+	if key == "" {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("env %s %s needs a key", envName, kind).Error(),
+		})
+	}
+}
+
+func (r *vs) validateObjectName(oc *OriginContext, field, name string) {
+	if name == "" {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("%s needs a name", field).Error(),
+		})
+		return
+	}
+	if msgs := validation.IsDNS1123Subdomain(name); len(msgs) > 0 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("%s invalid name %s: %s", field, name, strings.Join(msgs, ", ")).Error(),
+		})
+	}
+}
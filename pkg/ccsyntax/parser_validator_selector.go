@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// referencePlaceholder replaces the variable references in a selector such
+// that the syntax can be validated before the variables are known
+const referencePlaceholder = "ref"
+
+var fieldSelectorReference = regexp.MustCompile(`\$[^,=!\s]+`)
+
+func isReference(s string) bool {
+	return strings.Contains(s, "$")
+}
+
+func replaceReference(s string) string {
+	if isReference(s) {
+		return referencePlaceholder
+	}
+	return s
+}
+
+// validateQueryScope validates the label selector, namespace and field
+// selector of the input with the apimachinery parsers
+func (r *vs) validateQueryScope(oc *OriginContext, v *ctrlcfgv1alpha1.Input) {
+	if v.Selector != nil {
+		s := &metav1.LabelSelector{
+			MatchLabels: make(map[string]string, len(v.Selector.MatchLabels)),
+		}
+		for k, val := range v.Selector.MatchLabels {
+			s.MatchLabels[replaceReference(k)] = replaceReference(val)
+		}
+		for _, req := range v.Selector.MatchExpressions {
+			values := make([]string, 0, len(req.Values))
+			for _, val := range req.Values {
+				values = append(values, replaceReference(val))
+			}
+			s.MatchExpressions = append(s.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key:      replaceReference(req.Key),
+				Operator: req.Operator,
+				Values:   values,
+			})
+		}
+		if _, err := metav1.LabelSelectorAsSelector(s); err != nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("invalid selector: %s", err.Error()).Error(),
+			})
+		}
+	}
+	if v.Namespace != "" && !isReference(v.Namespace) {
+		for _, msg := range validation.IsDNS1123Label(v.Namespace) {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("invalid namespace %s: %s", v.Namespace, msg).Error(),
+			})
+		}
+	}
+	if v.FieldSelector != "" {
+		s := fieldSelectorReference.ReplaceAllString(v.FieldSelector, referencePlaceholder)
+		if _, err := fields.ParseSelector(s); err != nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("invalid fieldSelector %s: %s", v.FieldSelector, err.Error()).Error(),
+			})
+		}
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// queryScopeConfig queries the nodes of the pod, the scope of the query is
+// supplied by the test. The scope can refer to the pod and the name task.
+const queryScopeConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+    deletePipelineRef: delete
+pipelines:
+- name: apply
+  tasks:
+    name:
+      type: jq
+      input:
+        expression: $pod.metadata.name
+    nodes:
+      type: query
+      input:
+        resource:
+          apiVersion: v1
+          kind: Node
+%s
+- name: delete
+`
+
+func TestFieldSelectorReference(t *testing.T) {
+	cases := map[string][]string{
+		"spec.nodeName=node":                   nil,
+		"spec.nodeName=$node":                  {"$node"},
+		"spec.nodeName==$pod.spec.nodeName":    {"$pod.spec.nodeName"},
+		"metadata.name!=$a,spec.nodeName=$b.c": {"$a", "$b.c"},
+		"metadata.name=$a, spec.nodeName = $b": {"$a", "$b"},
+	}
+	for selector, want := range cases {
+		t.Run(selector, func(t *testing.T) {
+			got := fieldSelectorReference.FindAllString(selector, -1)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestQueryScope(t *testing.T) {
+	cases := map[string]struct {
+		scope string
+		// validate and parse are the prefixes of the expected results
+		validate string
+		parse    string
+		// upVertices are the vertices the query depends on
+		upVertices []string
+	}{
+		"None": {
+			upVertices: []string{"pod"},
+		},
+		"MatchLabels": {
+			scope:      "selector:\n  matchLabels:\n    pod: $pod.metadata.name",
+			upVertices: []string{"pod"},
+		},
+		"MatchExpressions": {
+			scope:      "selector:\n  matchExpressions:\n  - key: pod\n    operator: In\n    values: [static, $name]",
+			upVertices: []string{"name"},
+		},
+		"MatchExpressionsKey": {
+			scope:      "selector:\n  matchExpressions:\n  - key: $name\n    operator: Exists",
+			upVertices: []string{"name"},
+		},
+		"MatchExpressionsUnresolved": {
+			scope: "selector:\n  matchExpressions:\n  - key: pod\n    operator: In\n    values: [$missing]",
+			parse: "variable not found in gvar dag, varName: missing",
+		},
+		"MatchExpressionsOperator": {
+			scope:    "selector:\n  matchExpressions:\n  - key: pod\n    operator: Equals\n    values: [$name]",
+			validate: "invalid selector",
+		},
+		"MatchExpressionsExistsWithValues": {
+			scope:    "selector:\n  matchExpressions:\n  - key: pod\n    operator: Exists\n    values: [$name]",
+			validate: "invalid selector",
+		},
+		"Namespace": {
+			scope:      "namespace: default",
+			upVertices: []string{"pod"},
+		},
+		"NamespaceReference": {
+			scope:      "namespace: $pod.metadata.namespace",
+			upVertices: []string{"pod"},
+		},
+		"NamespaceInvalid": {
+			scope:    "namespace: Default_NS",
+			validate: "invalid namespace Default_NS",
+		},
+		"NamespaceUnresolved": {
+			scope: "namespace: $missing",
+			parse: "variable not found in gvar dag, varName: missing",
+		},
+		"FieldSelector": {
+			scope:      "fieldSelector: metadata.name=$name,spec.unschedulable!=true",
+			upVertices: []string{"name"},
+		},
+		"FieldSelectorInvalid": {
+			scope:    "fieldSelector: metadata.name",
+			validate: "invalid fieldSelector metadata.name",
+		},
+		"FieldSelectorUnresolved": {
+			scope: "fieldSelector: metadata.name=$missing",
+			parse: "variable not found in gvar dag, varName: missing",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			scope := ""
+			for _, line := range strings.Split(c.scope, "\n") {
+				if line != "" {
+					scope += "        " + line + "\n"
+				}
+			}
+			cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+			if err := yaml.Unmarshal([]byte(fmt.Sprintf(queryScopeConfig, strings.TrimSuffix(scope, "\n"))), cfg); err != nil {
+				t.Fatal(err)
+			}
+			p, result := NewParser("queryscope", cfg)
+			if c.validate != "" {
+				if len(result) != 1 || !strings.HasPrefix(result[0].Error, c.validate) {
+					t.Errorf("got validation results %v, want %s", result, c.validate)
+				}
+				return
+			}
+			if len(result) != 0 {
+				t.Fatalf("unexpected validation results: %v", result)
+			}
+			ceCtx, result := p.Parse()
+			if c.parse != "" {
+				if len(result) == 0 || !strings.HasPrefix(result[0].Error, c.parse) {
+					t.Errorf("got parse results %v, want %s", result, c.parse)
+				}
+				return
+			}
+			if len(result) != 0 {
+				t.Fatalf("unexpected parse results: %v", result)
+			}
+			dctx := ceCtx.GetDAGCtx(FOWFor, &schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, OperationApply)
+			got := dctx.DAG.GetUpVertexes("nodes")
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, c.upVertices) {
+				t.Errorf("got up vertices %v, want %v", got, c.upVertices)
+			}
+		})
+	}
+}
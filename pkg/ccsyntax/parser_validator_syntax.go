@@ -21,12 +21,12 @@ import (
 	"sync"
 
 	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
-	"github.com/fnrunner/fnutils/pkg/meta"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func (r *parser) ValidateSyntax() []Result {
 	vs := &vs{
+		ir:     r.ir,
 		result: []Result{},
 	}
 
@@ -47,6 +47,7 @@ func (r *parser) ValidateSyntax() []Result {
 }
 
 type vs struct {
+	ir     *configIR
 	mr     sync.RWMutex
 	result []Result
 }
@@ -71,13 +72,15 @@ func (r *vs) validateGvk(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *schem
 		r.recordResult(Result{
 			OriginContext: oc,
 			Error:         fmt.Errorf("a gvk must be present, got: %v", v).Error(),
+			Code:          CodeMissingGVK,
 		})
 	}
-	gvk, err := meta.GetGVKFromRuntimeRawExtension(v.Resource)
+	gvk, err := r.ir.GetGVK(v.Resource)
 	if err != nil {
 		r.recordResult(Result{
 			OriginContext: oc,
 			Error:         err.Error(),
+			Code:          CodeInvalidGVK,
 		})
 	}
 	return gvk
@@ -142,60 +145,28 @@ func (r *vs) validateFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
 		r.validateBlock(oc, v.Block)
 	}
 
-	// validate the function type
-	switch v.Type {
-	case ctrlcfgv1alpha1.MapType:
-		if v.Input.Key == "" {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         fmt.Errorf("key needs to be present in %s", v.Type).Error(),
-			})
-		}
-		if v.Input.Value == "" {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         fmt.Errorf("value needs to be present in %s", v.Type).Error(),
-			})
-		}
-	case ctrlcfgv1alpha1.SliceType:
-		if v.Input.Value == "" {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         fmt.Errorf("value needs to be present in %s", v.Type).Error(),
-			})
-		}
-	case ctrlcfgv1alpha1.QueryType:
-		if v.Input != nil {
-			if len(v.Input.Resource.Raw) == 0 {
-				r.recordResult(Result{
-					OriginContext: oc,
-					Error:         fmt.Errorf("gvk needs to be present in %s", v.Type).Error(),
-				})
-			} else {
-				_, err := meta.GetGVKFromRuntimeRawExtension(v.Input.Resource)
-				if err != nil {
-					r.recordResult(Result{
-						OriginContext: oc,
-						Error:         err.Error(),
-					})
-				}
-			}
-		} else {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         fmt.Errorf("input needs to be present in %s", v.Type).Error(),
-			})
-		}
+	// validate the input and output against the contract of the function type
+	r.validateContract(oc, v)
 
-	case ctrlcfgv1alpha1.GoTemplateType:
-		if len(v.Input.Resource.Raw) == 0 && v.Input.Template == "" {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         fmt.Errorf("resource or template need to be present in %s", v.Type).Error(),
-			})
-		}
+	// validate the type specific fields
+	for _, err := range getFunctionTypeHandler(v.Type).Validate(v) {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+		})
+	}
+	if v.ServiceRef != nil && v.Type != ctrlcfgv1alpha1.ServiceCallType {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("a serviceRef can only be used in %s, got %s", ctrlcfgv1alpha1.ServiceCallType, v.Type).Error(),
+		})
+	}
+
+	// validate input references
+	// e.g. check if a VALUE, KEY, INDEX is not used when no block is present
+	if v.Input != nil {
 		if len(v.Input.Resource.Raw) != 0 {
-			_, err := meta.GetGVKFromRuntimeRawExtension(v.Input.Resource)
+			_, err := r.ir.GetGVK(v.Input.Resource)
 			if err != nil {
 				r.recordResult(Result{
 					OriginContext: oc,
@@ -203,50 +174,33 @@ func (r *vs) validateFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
 				})
 			}
 		}
-	case ctrlcfgv1alpha1.BlockType:
-		// nothing to do since this is already validated
-	case ctrlcfgv1alpha1.ContainerType, ctrlcfgv1alpha1.WasmType:
-		if v.Executor.Exec == "" && v.Executor.Image == "" {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         fmt.Errorf("external functions need an image or exec, got %v", v).Error(),
-			})
-		}
-	default:
-	}
-
-	// validate input references
-	// e.g. check if a VALUE, KEY, INDEX is not used when no block is present
-	if v.Input == nil {
-		if !(v.Type == ctrlcfgv1alpha1.BlockType || v.Type == ctrlcfgv1alpha1.ContainerType) {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         fmt.Errorf("input is needed in a function %s", v.Type).Error(),
-			})
-		}
-	} else {
+		r.validateQueryScope(oc, v.Input)
 		if v.Input.Key != "" {
 			r.validateContext(oc, v, v.Input.Key)
 		}
 		if v.Input.Value != "" {
 			r.validateContext(oc, v, v.Input.Value)
 		}
-		for _, val := range v.Input.GenericInput {
-			r.validateContext(oc, v, val)
+		for _, k := range sortedKeys(v.Input.GenericInput) {
+			r.validateContext(oc, v, v.Input.GenericInput[k])
 		}
 	}
 
+	// a $ without a variable name cannot be resolved
+	r.validateEmptyReferences(oc, v)
+
 	// validate Ouput
 	// for external output a GVK needs to be present + validate the GVK syntax
 	if v.Output != nil {
-		for _, v := range v.Output {
-			if len(v.Resource.Raw) == 0 {
+		for _, varName := range sortedKeys(v.Output) {
+			v := v.Output[varName]
+			if v == nil || len(v.Resource.Raw) == 0 {
 				r.recordResult(Result{
 					OriginContext: oc,
 					Error:         fmt.Errorf("cannot use output without data").Error(),
 				})
 			} else {
-				_, err := meta.GetGVKFromRuntimeRawExtension(v.Resource)
+				_, err := r.ir.GetGVK(v.Resource)
 				if err != nil {
 					r.recordResult(Result{
 						OriginContext: oc,
@@ -257,6 +211,29 @@ func (r *vs) validateFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
 		}
 	}
 
+	// validate config, a config is either a string or a KRM object
+	r.validateConfig(oc, v)
+
+	// service settings are only supported in services
+	if v.HasServiceSettings() {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("replicas, portName and probes are only supported in services").Error(),
+		})
+	}
+	// runtime settings are only supported in external functions
+	switch v.Type {
+	case ctrlcfgv1alpha1.ContainerType, ctrlcfgv1alpha1.WasmType:
+		r.validateRuntimeSettings(oc, &v.Executor)
+	default:
+		if v.HasRuntimeSettings() {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("resources, env, timeout and retry are only supported in %s and %s functions, got %s", ctrlcfgv1alpha1.ContainerType, ctrlcfgv1alpha1.WasmType, v.Type).Error(),
+			})
+		}
+	}
+
 	// validate local vars -> TBD
 
 }
@@ -278,14 +255,15 @@ func (r *vs) validateServiceFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Funct
 	}
 	// for output a GVK needs to be present + validate the GVK syntax
 	if v.Output != nil {
-		for _, v := range v.Output {
-			if len(v.Resource.Raw) == 0 {
+		for _, varName := range sortedKeys(v.Output) {
+			v := v.Output[varName]
+			if v == nil || len(v.Resource.Raw) == 0 {
 				r.recordResult(Result{
 					OriginContext: oc,
 					Error:         fmt.Errorf("cannot use output without data").Error(),
 				})
 			} else {
-				_, err := meta.GetGVKFromRuntimeRawExtension(v.Resource)
+				_, err := r.ir.GetGVK(v.Resource)
 				if err != nil {
 					r.recordResult(Result{
 						OriginContext: oc,
@@ -300,6 +278,93 @@ func (r *vs) validateServiceFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Funct
 			Error:         fmt.Errorf("cannot use a service w/o output definition").Error(),
 		})
 	}
+	if v.Exec != "" {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("cannot use exec in services").Error(),
+		})
+	}
+	// a service is deployed and called by servicecall functions, the timeout
+	// and retry of an execution do not apply to the service manifests
+	if v.Timeout != nil || v.Retry != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("cannot use timeout or retry in services").Error(),
+		})
+	}
+	// validate the service deployment settings
+	r.validateServiceSettings(oc, &v.ServiceSettings)
+	r.validateRuntimeSettings(oc, &v.Executor)
+	r.validateConfig(oc, v)
+}
+
+// validateContract validates the input and output of the function against
+// the contract of its type
+func (r *vs) validateContract(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
+	c := getFunctionTypeHandler(v.Type).Contract()
+	if c == nil {
+		return
+	}
+	if v.Input == nil {
+		if c.InputRequired {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("input is needed in a function %s", v.Type).Error(),
+			})
+		}
+	} else {
+		for _, alternatives := range c.Required {
+			present := false
+			for _, f := range alternatives {
+				if hasInputField(v.Input, f) {
+					present = true
+				}
+			}
+			if !present {
+				r.recordResult(Result{
+					OriginContext: oc,
+					Error:         fmt.Errorf("%s needs to be present in %s", joinInputFields(alternatives), v.Type).Error(),
+				})
+			}
+		}
+		for _, f := range inputFields {
+			if hasInputField(v.Input, f) && !c.isAllowed(f) {
+				r.recordResult(Result{
+					OriginContext: oc,
+					Error:         fmt.Errorf("%s is not allowed in the input of %s", f, v.Type).Error(),
+				})
+			}
+		}
+	}
+	if c.OutputRequired && len(v.Output) == 0 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("output needs to be present in %s", v.Type).Error(),
+		})
+	}
+}
+
+func (r *vs) validateConfig(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
+	if !v.HasConfig() {
+		return
+	}
+	if _, ok := v.GetConfigString(); ok {
+		return
+	}
+	u, err := v.GetConfigObject()
+	if err != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("config must be a string or a KRM object: %s", err.Error()).Error(),
+		})
+		return
+	}
+	if u.GetAPIVersion() == "" || u.GetKind() == "" {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("an inline config must have an apiVersion and kind").Error(),
+		})
+	}
 }
 
 func (r *vs) validateBlock(oc *OriginContext, v ctrlcfgv1alpha1.Block) {
@@ -341,9 +406,23 @@ func (r *vs) validateBlock(oc *OriginContext, v ctrlcfgv1alpha1.Block) {
 //func (r *vs) validateService(oc *OriginContext, v *ctrlcfgv1.ControllerConfigFunction) {
 //}
 
+func (r *vs) validateEmptyReferences(oc *OriginContext, v *ctrlcfgv1alpha1.Function) {
+	for _, s := range getFunctionExpressions(v) {
+		for _, ref := range r.ir.GetReferences(s) {
+			if ref.Kind == RegularReferenceKind && ref.Value == "" {
+				r.recordResult(Result{
+					OriginContext: oc,
+					Error:         fmt.Errorf("empty reference in %s", s).Error(),
+					Code:          CodeUnresolvedReference,
+				})
+				break
+			}
+		}
+	}
+}
+
 func (r *vs) validateContext(oc *OriginContext, v *ctrlcfgv1alpha1.Function, s string) {
-	rfs := NewReferences()
-	refs := rfs.GetReferences(s)
+	refs := r.ir.GetReferences(s)
 	//fmt.Printf("validate ctxName: %s, value: %s, kind: %s, variable: %v\n", o.VertexName, s, value.Kind, value.Variable)
 	for _, ref := range refs {
 		switch ref.Kind {
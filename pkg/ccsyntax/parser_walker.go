@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"errors"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// ErrStopWalk stops the walk without failing it when returned by a visitor
+	ErrStopWalk = errors.New("stop walk")
+	// ErrSkipChildren skips the pipelines of a for, own or watch, the
+	// functions of a pipeline or the functions in a block when returned by a
+	// visitor
+	ErrSkipChildren = errors.New("skip children")
+)
+
+// Visitor is called for the elements of the controller config. The walk stops
+// at the first error a visitor returns, except for ErrSkipChildren.
+type Visitor interface {
+	// VisitGvkObject is called for every for, own and watch
+	VisitGvkObject(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) error
+	// VisitPipeline is called for the apply and delete pipeline of a for, own
+	// and watch
+	VisitPipeline(oc *OriginContext, v *ctrlcfgv1alpha1.Pipeline) error
+	// VisitFunction is called for every function in a pipeline, including the
+	// functions in a block, v is nil for an empty function
+	VisitFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error
+	// VisitService is called for every service, v is nil for an empty service
+	VisitService(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error
+}
+
+// VisitorFuncs implements a Visitor with optional functions, the elements
+// without function are skipped
+type VisitorFuncs struct {
+	GvkObjectFn func(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) error
+	PipelineFn  func(oc *OriginContext, v *ctrlcfgv1alpha1.Pipeline) error
+	FunctionFn  func(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error
+	ServiceFn   func(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error
+}
+
+func (r *VisitorFuncs) VisitGvkObject(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) error {
+	if r.GvkObjectFn == nil {
+		return nil
+	}
+	return r.GvkObjectFn(oc, v)
+}
+
+func (r *VisitorFuncs) VisitPipeline(oc *OriginContext, v *ctrlcfgv1alpha1.Pipeline) error {
+	if r.PipelineFn == nil {
+		return nil
+	}
+	return r.PipelineFn(oc, v)
+}
+
+func (r *VisitorFuncs) VisitFunction(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error {
+	if r.FunctionFn == nil {
+		return nil
+	}
+	return r.FunctionFn(oc, v)
+}
+
+func (r *VisitorFuncs) VisitService(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error {
+	if r.ServiceFn == nil {
+		return nil
+	}
+	return r.ServiceFn(oc, v)
+}
+
+// Walk walks the controller config and calls the visitor for its elements.
+// The elements are visited in the order of the intermediate representation the
+// parser phases walk: the fors, owns, watches, functions and services sorted by
+// name, the apply pipeline before the delete pipeline and the vars before the
+// tasks of a pipeline. An empty function or service is visited with a nil
+// function. Every visit gets its own copy of the origin context.
+// Walk returns the first error of the visitor, except for ErrStopWalk.
+func Walk(cfg *ctrlcfgv1alpha1.ControllerConfigSpec, v Visitor) error {
+	if cfg == nil || v == nil {
+		return nil
+	}
+	return newConfigIR(cfg).walk(v)
+}
+
+// Walk walks the controller config of the parser with the visitor
+func (r *parser) Walk(v Visitor) error {
+	if v == nil {
+		return nil
+	}
+	return r.ir.walk(v)
+}
+
+// walk calls the visitor for the nodes of the intermediate representation.
+// When a visitor returns ErrSkipChildren the nodes below the visited node are
+// skipped.
+func (r *configIR) walk(v Visitor) error {
+	var gvk *schema.GroupVersionKind
+	skipDepth := -1
+	for _, n := range r.nodes {
+		depth := n.depth()
+		if skipDepth >= 0 {
+			if depth > skipDepth {
+				continue
+			}
+			skipDepth = -1
+		}
+		var err error
+		switch n.kind {
+		case irNodeGvkObject:
+			gvk, _ = r.GetGVK(n.gvkObject.Resource)
+			err = v.VisitGvkObject(n.position(gvk), n.gvkObject)
+		case irNodePipelinePreHook:
+			err = v.VisitPipeline(n.position(gvk), n.pipeline)
+		case irNodeFunctionElement:
+			oc := n.position(gvk)
+			if n.functionElement == nil {
+				err = v.VisitFunction(oc, nil)
+				break
+			}
+			if n.functionElement.Type == ctrlcfgv1alpha1.BlockType {
+				oc.Block = true
+			}
+			err = v.VisitFunction(oc, &n.functionElement.Function)
+		case irNodeService:
+			err = v.VisitService(n.position(nil), n.function)
+		}
+		if errors.Is(err, ErrSkipChildren) {
+			skipDepth = depth
+			continue
+		}
+		if errors.Is(err, ErrStopWalk) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
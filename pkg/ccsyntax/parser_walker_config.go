@@ -17,6 +17,8 @@ limitations under the License.
 package ccsyntax
 
 import (
+	"context"
+
 	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -40,6 +42,9 @@ type functionBlockFn func(oc *OriginContext, v *ctrlcfgv1alpha1.FunctionElement)
 type serviceFn func(oc *OriginContext, v *ctrlcfgv1alpha1.Function)
 
 type WalkConfig struct {
+	// ctx stops the walk when it is done
+	ctx context.Context
+
 	cfgPreHookFn    cfgPreHookFn
 	cfgPostHookFn   cfgPostHookFn
 	gvkObjectFn     gvkObjectFn
@@ -53,131 +58,66 @@ type WalkConfig struct {
 	serviceFn              serviceFn
 }
 
+// canceled returns true if the context of the walk is done
+func (fnc *WalkConfig) canceled() bool {
+	return fnc.ctx != nil && fnc.ctx.Err() != nil
+}
+
+// walkControllerConfig walks the intermediate representation of the config.
+// The pipelines of a for, own and watch are only walked when a gvkObjectFn is
+// supplied, the gvk it returns is set in the origin context of the elements in
+// the pipelines.
 func (r *parser) walkControllerConfig(fnc *WalkConfig) {
 	// process config entry
 	if fnc.cfgPreHookFn != nil {
 		fnc.cfgPreHookFn(r.cCfg)
 	}
 
-	// process for, own, watch
-	idx := 0
-	for vertexName, v := range r.cCfg.GetFors() {
-		// we run this once for apply and once for delete
-		oc := &OriginContext{FOWS: FOWFor, RootVertexName: vertexName, Origin: OriginFow, VertexName: vertexName}
-		r.processGvkObject(fnc, oc, v)
-		idx++
-
-	}
-	idx = 0
-	for vertexName, v := range r.cCfg.GetOwns() {
-		// For Own the oepration is irrelevant
-		oc := &OriginContext{FOWS: FOWOwn, RootVertexName: vertexName, Origin: OriginFow, VertexName: vertexName}
-		r.processGvkObject(fnc, oc, v)
-		idx++
-	}
-	idx = 0
-	for vertexName, v := range r.cCfg.GetWatches() {
-		// we run this only for operation apply, NOT for delete
-		oc := &OriginContext{FOWS: FOWWatch, RootVertexName: vertexName, Origin: OriginFow, VertexName: vertexName}
-		r.processGvkObject(fnc, oc, v)
-	}
-
-	if fnc.serviceFn != nil {
-		//fmt.Printf("services: %v\n", r.cCfg.GetServices())
-		for vertexName, fn := range r.cCfg.GetServices() {
-			oc := &OriginContext{FOWS: FOWService, RootVertexName: vertexName, Origin: OriginService, VertexName: vertexName}
-			fnc.serviceFn(oc, fn)
+	var gvk *schema.GroupVersionKind
+	for _, n := range r.ir.nodes {
+		if fnc.canceled() {
+			return
 		}
-	}
-
-	if fnc.cfgPostHookFn != nil {
-		fnc.cfgPostHookFn(r.cCfg)
-	}
-}
-
-func (r *parser) processGvkObject(fnc *WalkConfig, oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) {
-	if fnc.gvkObjectFn != nil {
-		gvk := fnc.gvkObjectFn(oc, v)
-		oc.GVK = gvk
-		oc.Operation = OperationApply
-		applyPipeline := r.cCfg.GetPipeline(v.ApplyPipelineRef)
-		if applyPipeline == nil {
-			if fnc.emptyPipelineFn != nil {
-				fnc.emptyPipelineFn(oc, v)
-			}
-		} else {
-			fnc.walkPipeline(oc, applyPipeline)
+		if n.kind != irNodeService && fnc.gvkObjectFn == nil {
+			continue
 		}
-
-		oc.Operation = OperationDelete
-		deletePipeline := r.cCfg.GetPipeline(v.DeletePipelineRef)
-		if deletePipeline == nil {
+		switch n.kind {
+		case irNodeGvkObject:
+			gvk = fnc.gvkObjectFn(n.position(nil), n.gvkObject)
+		case irNodeEmptyPipeline:
 			if fnc.emptyPipelineFn != nil {
-				fnc.emptyPipelineFn(oc, v)
+				fnc.emptyPipelineFn(n.position(gvk), n.gvkObject)
+			}
+		case irNodePipelinePreHook:
+			if fnc.pipelinePreHookFn != nil {
+				fnc.pipelinePreHookFn(n.position(gvk), n.pipeline)
+			}
+		case irNodePipelinePostHook:
+			if fnc.pipelinePostHookFn != nil {
+				fnc.pipelinePostHookFn(n.position(gvk), n.pipeline)
+			}
+		case irNodeFunctionElement:
+			fnc.walkFunctionElement(n.position(gvk), n.functionElement)
+		case irNodeService:
+			if n.function == nil {
+				if fnc.emptyFunctionElementFn != nil {
+					fnc.emptyFunctionElementFn(n.position(nil))
+				}
+				continue
+			}
+			if fnc.serviceFn != nil {
+				fnc.serviceFn(n.position(nil), n.function)
 			}
-		} else {
-			fnc.walkPipeline(oc, deletePipeline)
-		}
-	}
-}
-
-func (fnc *WalkConfig) walkPipeline(oc *OriginContext, v *ctrlcfgv1alpha1.Pipeline) {
-	pipelineName := v.Name
-	if fnc.pipelinePreHookFn != nil {
-		oc := &OriginContext{
-			FOWS:           oc.FOWS,
-			RootVertexName: oc.RootVertexName,
-			Operation:      oc.Operation,
-			GVK:            oc.GVK,
-			Pipeline:       pipelineName,
-			Origin:         oc.Origin,
-			VertexName:     oc.VertexName,
-		}
-		fnc.pipelinePreHookFn(oc, v)
-	}
-
-	for vertexName, v := range v.Vars {
-		oc := &OriginContext{
-			FOWS:           oc.FOWS,
-			RootVertexName: oc.RootVertexName,
-			Operation:      oc.Operation,
-			GVK:            oc.GVK,
-			Pipeline:       pipelineName,
-			Origin:         OriginVariable,
-			VertexName:     vertexName,
-			LocalVars:      v.Vars,
-		}
-		fnc.walkFunctionElement(oc, v)
-	}
-
-	for vertexName, v := range v.Tasks {
-		oc := &OriginContext{
-			FOWS:           oc.FOWS,
-			RootVertexName: oc.RootVertexName,
-			Operation:      oc.Operation,
-			GVK:            oc.GVK,
-			Pipeline:       pipelineName,
-			Origin:         OriginFunction,
-			VertexName:     vertexName,
-			LocalVars:      v.Vars,
 		}
-		fnc.walkFunctionElement(oc, v)
 	}
 
-	if fnc.pipelinePostHookFn != nil {
-		oc := &OriginContext{
-			FOWS:           oc.FOWS,
-			RootVertexName: oc.RootVertexName,
-			Operation:      oc.Operation,
-			GVK:            oc.GVK,
-			Pipeline:       pipelineName,
-			Origin:         oc.Origin,
-			VertexName:     oc.VertexName,
-		}
-		fnc.pipelinePostHookFn(oc, v)
+	if fnc.cfgPostHookFn != nil {
+		fnc.cfgPostHookFn(r.cCfg)
 	}
 }
 
+// walkFunctionElement processes a function, the functions in the block of a
+// function are separate elements in the intermediate representation
 func (fnc *WalkConfig) walkFunctionElement(oc *OriginContext, v *ctrlcfgv1alpha1.FunctionElement) {
 	if v == nil {
 		if fnc.emptyFunctionElementFn != nil {
@@ -191,36 +131,14 @@ func (fnc *WalkConfig) walkFunctionElement(oc *OriginContext, v *ctrlcfgv1alpha1
 			// use to validate the function block
 			fnc.functionBlockFn(oc, v)
 		}
-		// for a block function we allocate a new dag
-		//if v.HasBlock() {
-		//	oc.BlockDAG = dag.New()
-		//}
 		// the function in the function block is treated as a regular function
 		if fnc.functionFn != nil {
 			oc.Block = true
 			fnc.functionFn(oc, &v.Function)
 		}
-
-		for vertexName, v := range v.FunctionBlock {
-			oc := &OriginContext{
-				FOWS:            oc.FOWS,
-				RootVertexName:  oc.RootVertexName,
-				Operation:       oc.Operation,
-				GVK:             oc.GVK,
-				Pipeline:        oc.Pipeline,
-				Origin:          oc.Origin,
-				Block:           true,
-				BlockIndex:      oc.BlockIndex + 1,
-				BlockVertexName: oc.VertexName,
-				VertexName:      vertexName,
-				LocalVars:       oc.LocalVars,
-			}
-			fnc.walkFunctionElement(oc, v)
-		}
-	} else {
-		if fnc.functionFn != nil {
-			//fmt.Printf("oc function: %v\n", oc)
-			fnc.functionFn(oc, &v.Function)
-		}
+		return
+	}
+	if fnc.functionFn != nil {
+		fnc.functionFn(oc, &v.Function)
 	}
 }
@@ -0,0 +1,175 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"reflect"
+	"testing"
+
+	ctrlcfgv1alpha1 "github.com/fnrunner/fnsyntax/apis/controllerconfig/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// the block has local vars that differ from the vars of its functions, the
+// empty task and service are visited with a nil function
+const walkConfig = `
+for:
+  pod:
+    resource:
+      apiVersion: v1
+      kind: Pod
+    applyPipelineRef: apply
+own:
+  cm:
+    resource:
+      apiVersion: v1
+      kind: ConfigMap
+    applyPipelineRef: apply
+pipelines:
+- name: apply
+  vars:
+    name:
+      type: jq
+      input:
+        expression: $pod.metadata.name
+  tasks:
+    empty: null
+    block:
+      type: block
+      vars:
+        a: $name
+      condition:
+        expression: $name != ""
+      block:
+        inner:
+          type: jq
+          vars:
+            b: $name
+          input:
+            expression: $b
+          output:
+            inner:
+              internal: true
+services:
+  s: null
+`
+
+func TestWalkMatchesInternalWalk(t *testing.T) {
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal([]byte(walkConfig), cfg); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := NewParser("test", cfg)
+	r := p.(*parser)
+
+	want := []*OriginContext{}
+	r.walkControllerConfig(&WalkConfig{
+		gvkObjectFn: func(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) *schema.GroupVersionKind {
+			gvk, _ := r.ir.GetGVK(v.Resource)
+			oc.GVK = gvk
+			want = append(want, oc)
+			return gvk
+		},
+		pipelinePreHookFn:      func(oc *OriginContext, v *ctrlcfgv1alpha1.Pipeline) { want = append(want, oc) },
+		emptyFunctionElementFn: func(oc *OriginContext) { want = append(want, oc) },
+		functionFn:             func(oc *OriginContext, v *ctrlcfgv1alpha1.Function) { want = append(want, oc) },
+		serviceFn:              func(oc *OriginContext, v *ctrlcfgv1alpha1.Function) { want = append(want, oc) },
+	})
+
+	got := []*OriginContext{}
+	visit := func(oc *OriginContext) error {
+		got = append(got, oc)
+		return nil
+	}
+	if err := p.Walk(&VisitorFuncs{
+		GvkObjectFn: func(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) error { return visit(oc) },
+		PipelineFn:  func(oc *OriginContext, v *ctrlcfgv1alpha1.Pipeline) error { return visit(oc) },
+		FunctionFn:  func(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error { return visit(oc) },
+		ServiceFn:   func(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error { return visit(oc) },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d visits, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("visit %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWalkSkipChildren(t *testing.T) {
+	cfg := &ctrlcfgv1alpha1.ControllerConfigSpec{}
+	if err := yaml.Unmarshal([]byte(walkConfig), cfg); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		v    *VisitorFuncs
+		want []string
+	}{
+		"GvkObject": {
+			v: &VisitorFuncs{GvkObjectFn: func(oc *OriginContext, v *ctrlcfgv1alpha1.GvkObject) error {
+				if oc.RootVertexName == "pod" {
+					return ErrSkipChildren
+				}
+				return nil
+			}},
+			want: []string{"cm/name", "cm/block", "cm/inner", "cm/empty"},
+		},
+		"Block": {
+			v: &VisitorFuncs{FunctionFn: func(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error {
+				if oc.VertexName == "block" {
+					return ErrSkipChildren
+				}
+				return nil
+			}},
+			want: []string{"pod/name", "pod/empty", "cm/name", "cm/empty"},
+		},
+		"Stop": {
+			v: &VisitorFuncs{FunctionFn: func(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error {
+				if oc.VertexName == "empty" {
+					return ErrStopWalk
+				}
+				return nil
+			}},
+			want: []string{"pod/name", "pod/block", "pod/inner"},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := []string{}
+			fn := c.v.FunctionFn
+			c.v.FunctionFn = func(oc *OriginContext, v *ctrlcfgv1alpha1.Function) error {
+				if fn != nil {
+					if err := fn(oc, v); err != nil {
+						return err
+					}
+				}
+				got = append(got, oc.RootVertexName+"/"+oc.VertexName)
+				return nil
+			}
+			if err := Walk(cfg, c.v); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 Nokia.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccsyntax
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzGetReferences(f *testing.F) {
+	for _, s := range []string{
+		"$topoDef",
+		"$topoDef.spec.name",
+		"$masterTemplates | .[] as $_a | select(.metadata.name == $_a)",
+		"$VALUE.name",
+		"$",
+		"$$",
+		"$.",
+		"spec.nodeName=$node,metadata.name!=$name",
+		"",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, ref := range NewReferences().GetReferences(s) {
+			if !strings.Contains(s, "$"+ref.Value) {
+				t.Errorf("reference %q is not part of %q", ref.Value, s)
+			}
+			switch ref.Kind {
+			case RangeReferenceKind:
+				if ref.Value != ValueKey && ref.Value != KeyKey && ref.Value != IndexKey {
+					t.Errorf("unexpected range reference %q", ref.Value)
+				}
+			case RegularReferenceKind:
+			default:
+				t.Errorf("unexpected reference kind %q", ref.Kind)
+			}
+		}
+	})
+}